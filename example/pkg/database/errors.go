@@ -5,24 +5,20 @@ import (
 
 	"github.com/go-sql-driver/mysql"
 	"github.com/jackc/pgx/v5/pgconn"
-	"github.com/mattn/go-sqlite3"
 )
 
-// ErrUnsupportedDriver is returned when the database driver is not recognized.
-var ErrUnsupportedDriver = errors.New("unsupported database driver")
-
-// IsDeadlockError checks if the error is a deadlock or "database busy" error.
+// IsDeadlockError checks if the error is a deadlock, serialization failure or "database busy" error.
 func IsDeadlockError(err error) bool {
 	if err == nil {
 		return false
 	}
 
-	var sqliteErr sqlite3.Error
-	if errors.As(err, &sqliteErr) {
-		return sqliteErr.Code == sqlite3.ErrBusy ||
-			sqliteErr.Code == sqlite3.ErrLocked
+	// Errors returned by the Querier are classified by the generated wrappers.
+	if errors.Is(err, ErrDeadlock) || errors.Is(err, ErrSerialization) {
+		return true
 	}
 
+	// Raw driver errors, e.g. from q.DB() or hand-written SQL, are not.
 	var pgErr *pgconn.PgError
 	if errors.As(err, &pgErr) {
 		return pgErr.Code == "40001" || pgErr.Code == "40P01"
@@ -33,7 +29,7 @@ func IsDeadlockError(err error) bool {
 		return mysqlErr.Number == 1213 || mysqlErr.Number == 1205
 	}
 
-	return false
+	return isSQLiteDeadlockError(err)
 }
 
 // IsDuplicateKeyError checks if the error is a duplicate key / unique constraint violation.
@@ -42,9 +38,8 @@ func IsDuplicateKeyError(err error) bool {
 		return false
 	}
 
-	var sqliteErr sqlite3.Error
-	if errors.As(err, &sqliteErr) {
-		return sqliteErr.Code == sqlite3.ErrConstraint
+	if errors.Is(err, ErrDuplicateKey) {
+		return true
 	}
 
 	var pgErr *pgconn.PgError
@@ -57,7 +52,7 @@ func IsDuplicateKeyError(err error) bool {
 		return mysqlErr.Number == 1062
 	}
 
-	return false
+	return isSQLiteDuplicateKeyError(err)
 }
 
 // IsNotFoundError checks if the error indicates a row was not found.
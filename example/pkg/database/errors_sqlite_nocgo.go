@@ -0,0 +1,9 @@
+//go:build !cgo
+
+package database
+
+// go-sqlite3 requires cgo: without it, no SQLite error can be returned.
+
+func isSQLiteDeadlockError(error) bool { return false }
+
+func isSQLiteDuplicateKeyError(error) bool { return false }
@@ -1,7 +1,10 @@
 // Code generated by sqlc-multi-db. DO NOT EDIT.
 package database
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
 
 var (
 	// ErrNotFound is returned when a query returns no rows.
@@ -9,4 +12,72 @@ var (
 
 	// ErrMismatchedSlices is returned when bulk operations receive slices of different lengths.
 	ErrMismatchedSlices = errors.New("mismatched slice lengths")
+
+	// ErrNotSupported is returned by methods that are not available on the current database engine.
+	ErrNotSupported = errors.New("not supported by this database engine")
+)
+
+// Classification sentinels. Wrappers wrap driver errors with the matching sentinel, so
+// errors.Is(err, ErrDuplicateKey) works on every engine while errors.As still reaches
+// the driver's own error type.
+var (
+	// ErrDuplicateKey is a unique or primary key constraint violation.
+	ErrDuplicateKey = errors.New("duplicate key")
+
+	// ErrForeignKeyViolation is a foreign key constraint violation.
+	ErrForeignKeyViolation = errors.New("foreign key violation")
+
+	// ErrCheckViolation is a CHECK constraint violation.
+	ErrCheckViolation = errors.New("check constraint violation")
+
+	// ErrNotNullViolation is a NOT NULL constraint violation.
+	ErrNotNullViolation = errors.New("not null violation")
+
+	// ErrDeadlock is a deadlock, a lock wait timeout or, on SQLite, a busy or locked database.
+	ErrDeadlock = errors.New("deadlock")
+
+	// ErrSerialization is a serialization failure of a concurrent transaction.
+	ErrSerialization = errors.New("serialization failure")
 )
+
+// classifiedError attaches a classification sentinel to a driver error.
+type classifiedError struct {
+	kind error
+	err  error
+}
+
+func (e *classifiedError) Error() string {
+	return e.err.Error()
+}
+
+func (e *classifiedError) Unwrap() []error {
+	return []error{e.kind, e.err}
+}
+
+// classify wraps err with kind, unless err is already classified.
+func classify(kind, err error) error {
+	var ce *classifiedError
+	if errors.As(err, &ce) {
+		return err
+	}
+
+	return &classifiedError{kind: kind, err: err}
+}
+
+// BulkError is returned when a bulk operation fails part way through. Index is the
+// element that failed; when elements are sent in chunks (multi-row INSERTs or
+// @bulk-chunk) it is the first element of the failing chunk. Err is the cause, which may
+// be the context's error if it was canceled between elements.
+type BulkError struct {
+	Method string
+	Index  int
+	Err    error
+}
+
+func (e *BulkError) Error() string {
+	return fmt.Sprintf("%s: element %d: %v", e.Method, e.Index, e.Err)
+}
+
+func (e *BulkError) Unwrap() error {
+	return e.Err
+}
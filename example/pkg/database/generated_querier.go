@@ -82,5 +82,28 @@ type Querier interface {
 	UpdateBook(ctx context.Context, arg UpdateBookParams) (Book, error)
 
 	WithTx(tx *sql.Tx) Querier
+	// WithDBTX returns a Querier running every query on db, such as a *sql.Conn pinned
+	// for session settings or advisory locks.
+	WithDBTX(db DBTX) Querier
+	// BeginTx starts a transaction, or a savepoint when the Querier is already in one.
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (TxQuerier, error)
 	DB() *sql.DB
 }
+
+// TxQuerier is a Querier scoped to a transaction started with BeginTx.
+type TxQuerier interface {
+	Querier
+	// Commit commits the transaction, or releases the savepoint of a nested BeginTx.
+	Commit() error
+	// Rollback rolls the transaction back, or rolls back to the savepoint of a nested
+	// BeginTx. It returns sql.ErrTxDone after Commit, so it can be deferred.
+	Rollback() error
+}
+
+// DBTX is what queries run on: a *sql.DB, *sql.Tx or *sql.Conn.
+type DBTX interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
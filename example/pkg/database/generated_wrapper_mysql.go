@@ -6,6 +6,9 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
 
 	"github.com/kalbasit/sqlc-multi-db/example/pkg/database/mysqldb"
 )
@@ -15,7 +18,88 @@ type mysqlWrapper struct {
 	adapter *mysqldb.Adapter
 }
 
-func (w *mysqlWrapper) AddBookTag(ctx context.Context, arg AddBookTagParams) error {
+// NewMySQLQuerier returns a Querier running queries on the MySQL database db.
+func NewMySQLQuerier(db *sql.DB) Querier {
+	return &mysqlWrapper{adapter: mysqldb.NewAdapter(db)}
+}
+
+// NewMySQLQuerierFromAdapter returns a Querier running queries through an
+// existing mysqldb.Adapter, e.g. one scoped to a transaction with WithTx.
+func NewMySQLQuerierFromAdapter(adapter *mysqldb.Adapter) Querier {
+	return &mysqlWrapper{adapter: adapter}
+}
+
+// mysqlBulkSavepoints numbers the savepoints of runBulk calls inside an existing
+// transaction, so that bulk calls nested in BeginTx or RunInTx savepoints never share a name.
+var mysqlBulkSavepoints atomic.Uint64
+
+// mysqlMaxPlaceholders is the number of bind parameters a single statement may use.
+const mysqlMaxPlaceholders = 65535
+
+// runBulk runs a bulk operation made of several statements atomically. Outside a
+// transaction it begins one (see beginTx); inside WithTx it uses a savepoint so a failed
+// bulk call is rolled back without aborting the caller's transaction.
+func (w *mysqlWrapper) runBulk(ctx context.Context, fn func(a *mysqldb.Adapter) error) error {
+	if tx, ok := w.adapter.DBTX().(*sql.Tx); ok {
+		name := fmt.Sprintf("sqlc_multi_db_bulk_%d", mysqlBulkSavepoints.Add(1))
+
+		if _, err := tx.ExecContext(ctx, "SAVEPOINT "+name); err != nil {
+			return err
+		}
+
+		if err := fn(w.adapter); err != nil {
+			_, _ = tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+name)
+			_, _ = tx.ExecContext(ctx, "RELEASE SAVEPOINT "+name)
+
+			return err
+		}
+
+		_, err := tx.ExecContext(ctx, "RELEASE SAVEPOINT "+name)
+
+		return err
+	}
+
+	tx, err := w.beginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := fn(w.adapter.WithTx(tx)); err != nil {
+		_ = tx.Rollback()
+
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// classifyMySQLError wraps a MySQL driver error with the matching
+// classification sentinel, such as ErrDuplicateKey. Other errors are returned unchanged.
+// The driver error is read by mysqlErrorNumber, generated in its own build-tagged file.
+func classifyMySQLError(err error) error {
+	number, ok := mysqlErrorNumber(err)
+	if !ok {
+		return err
+	}
+
+	switch number {
+	case 1062, 1586: // ER_DUP_ENTRY, ER_DUP_ENTRY_WITH_KEY_NAME
+		return classify(ErrDuplicateKey, err)
+	case 1216, 1217, 1451, 1452: // ER_NO_REFERENCED_ROW, ER_ROW_IS_REFERENCED (and _2)
+		return classify(ErrForeignKeyViolation, err)
+	case 3819: // ER_CHECK_CONSTRAINT_VIOLATED
+		return classify(ErrCheckViolation, err)
+	case 1048, 1364: // ER_BAD_NULL_ERROR, ER_NO_DEFAULT_FOR_FIELD
+		return classify(ErrNotNullViolation, err)
+	case 1205, 1213: // ER_LOCK_WAIT_TIMEOUT, ER_LOCK_DEADLOCK
+		return classify(ErrDeadlock, err)
+	}
+
+	return err
+}
+
+func (w *mysqlWrapper) AddBookTag(ctx context.Context, arg AddBookTagParams) (err error) {
+	defer func() { err = classifyMySQLError(err) }()
 	/* --- Auto-Loop for Bulk Insert on Non-Postgres --- */
 
 	return w.adapter.AddBookTag(ctx, mysqldb.AddBookTagParams{
@@ -24,28 +108,47 @@ func (w *mysqlWrapper) AddBookTag(ctx context.Context, arg AddBookTagParams) err
 	})
 }
 
-func (w *mysqlWrapper) AddBookTags(ctx context.Context, arg AddBookTagsParams) error {
+func (w *mysqlWrapper) AddBookTags(ctx context.Context, arg AddBookTagsParams) (err error) {
+	defer func() { err = classifyMySQLError(err) }()
 	/* --- Auto-Loop for Bulk Insert on Non-Postgres --- */
 
 	if len(arg.TagIds) != len(arg.BookIds) {
 		return ErrMismatchedSlices
 	}
-	for i, v := range arg.BookIds {
-		_ = i
-		err := w.adapter.AddBookTag(ctx, mysqldb.AddBookTagParams{
-			BookID: v,
-
-			TagID: arg.TagIds[i],
-		},
-		)
-		if err != nil {
-			return err
+	return w.runBulk(ctx, func(a *mysqldb.Adapter) error {
+		// AddBookTag is repeated as a multi-row INSERT, chunked to stay
+		// within the engine's placeholder limit.
+		const rowPlaceholders = 2
+
+		chunkSize := mysqlMaxPlaceholders / rowPlaceholders
+		for start := 0; start < len(arg.BookIds); start += chunkSize {
+			if err := ctx.Err(); err != nil {
+				return &BulkError{Method: "AddBookTags", Index: start, Err: err}
+			}
+
+			end := min(start+chunkSize, len(arg.BookIds))
+			args := make([]any, 0, (end-start)*rowPlaceholders)
+			for i := start; i < end; i++ {
+				v := arg.BookIds[i]
+				row := mysqldb.AddBookTagParams{
+					BookID: v,
+					TagID:  arg.TagIds[i],
+				}
+				args = append(args, row.BookID, row.TagID)
+			}
+
+			query := "INSERT INTO book_tags (`book_id`, `tag_id`) VALUES " + "(?, ?)" +
+				strings.Repeat(", (?, ?)", end-start-1)
+			if _, err := a.DBTX().ExecContext(ctx, query, args...); err != nil {
+				return &BulkError{Method: "AddBookTags", Index: start, Err: err}
+			}
 		}
-	}
-	return nil
+		return nil
+	})
 }
 
-func (w *mysqlWrapper) CreateBook(ctx context.Context, arg CreateBookParams) (Book, error) {
+func (w *mysqlWrapper) CreateBook(ctx context.Context, arg CreateBookParams) (_ Book, err error) {
+	defer func() { err = classifyMySQLError(err) }()
 	/* --- Auto-Loop for Bulk Insert on Non-Postgres --- */
 
 	// MySQL does not support RETURNING for INSERTs.
@@ -75,11 +178,17 @@ func (w *mysqlWrapper) CreateBook(ctx context.Context, arg CreateBookParams) (Bo
 	// MySQL REPEATABLE READ: if ON DUPLICATE KEY UPDATE fired (another transaction
 	// already committed this row), the current transaction's MVCC snapshot may not
 	// see it via GetByID. Fall back to a non-transactional lookup on the raw DB
-	// connection, which always reads the latest committed data.
+	// connection, which always reads the latest committed data. Outside a
+	// transaction the lookup above already did.
+	if w.currentTx() == nil {
+		return Book{}, err
+	}
+
 	return (&mysqlWrapper{adapter: mysqldb.NewAdapter(w.adapter.DB())}).GetBookByID(ctx, id)
 }
 
-func (w *mysqlWrapper) CreateTag(ctx context.Context, name string) (Tag, error) {
+func (w *mysqlWrapper) CreateTag(ctx context.Context, name string) (_ Tag, err error) {
+	defer func() { err = classifyMySQLError(err) }()
 	/* --- Auto-Loop for Bulk Insert on Non-Postgres --- */
 
 	// MySQL does not support RETURNING for INSERTs.
@@ -105,17 +214,24 @@ func (w *mysqlWrapper) CreateTag(ctx context.Context, name string) (Tag, error)
 	// MySQL REPEATABLE READ: if ON DUPLICATE KEY UPDATE fired (another transaction
 	// already committed this row), the current transaction's MVCC snapshot may not
 	// see it via GetByID. Fall back to a non-transactional lookup on the raw DB
-	// connection, which always reads the latest committed data.
+	// connection, which always reads the latest committed data. Outside a
+	// transaction the lookup above already did.
+	if w.currentTx() == nil {
+		return Tag{}, err
+	}
+
 	return (&mysqlWrapper{adapter: mysqldb.NewAdapter(w.adapter.DB())}).GetTagByID(ctx, id)
 }
 
-func (w *mysqlWrapper) DeleteBook(ctx context.Context, id int64) error {
+func (w *mysqlWrapper) DeleteBook(ctx context.Context, id int64) (err error) {
+	defer func() { err = classifyMySQLError(err) }()
 	/* --- Auto-Loop for Bulk Insert on Non-Postgres --- */
 
 	return w.adapter.DeleteBook(ctx, id)
 }
 
-func (w *mysqlWrapper) GetBook(ctx context.Context, id int64) (Book, error) {
+func (w *mysqlWrapper) GetBook(ctx context.Context, id int64) (_ Book, err error) {
+	defer func() { err = classifyMySQLError(err) }()
 	/* --- Auto-Loop for Bulk Insert on Non-Postgres --- */
 
 	res, err := w.adapter.GetBook(ctx, id)
@@ -131,27 +247,23 @@ func (w *mysqlWrapper) GetBook(ctx context.Context, id int64) (Book, error) {
 	// Convert Single Domain Struct
 
 	return Book{
-		ID: res.ID,
-
-		Title: res.Title,
-
-		Author: res.Author,
-
+		ID:          res.ID,
+		Title:       res.Title,
+		Author:      res.Author,
 		Description: res.Description,
-
-		CreatedAt: res.CreatedAt,
-
-		UpdatedAt: res.UpdatedAt,
+		CreatedAt:   res.CreatedAt,
+		UpdatedAt:   res.UpdatedAt,
 	}, nil
 }
 
-func (w *mysqlWrapper) GetBookByID(ctx context.Context, id int64) (Book, error) {
+func (w *mysqlWrapper) GetBookByID(ctx context.Context, id int64) (_ Book, err error) {
+	defer func() { err = classifyMySQLError(err) }()
 	/* --- Auto-Loop for Bulk Insert on Non-Postgres --- */
 
 	query := "SELECT `id`, `title`, `author`, `description`, `created_at`, `updated_at` FROM books WHERE id = ?"
 	row := w.adapter.DBTX().QueryRowContext(ctx, query, id)
 	var res mysqldb.Book
-	err := row.Scan(
+	err = row.Scan(
 
 		&res.ID,
 
@@ -175,21 +287,17 @@ func (w *mysqlWrapper) GetBookByID(ctx context.Context, id int64) (Book, error)
 	// Convert to Domain Struct
 
 	return Book{
-		ID: res.ID,
-
-		Title: res.Title,
-
-		Author: res.Author,
-
+		ID:          res.ID,
+		Title:       res.Title,
+		Author:      res.Author,
 		Description: res.Description,
-
-		CreatedAt: res.CreatedAt,
-
-		UpdatedAt: res.UpdatedAt,
+		CreatedAt:   res.CreatedAt,
+		UpdatedAt:   res.UpdatedAt,
 	}, nil
 }
 
-func (w *mysqlWrapper) GetBookTags(ctx context.Context, bookID int64) ([]Tag, error) {
+func (w *mysqlWrapper) GetBookTags(ctx context.Context, bookID int64) (_ []Tag, err error) {
+	defer func() { err = classifyMySQLError(err) }()
 	/* --- Auto-Loop for Bulk Insert on Non-Postgres --- */
 
 	res, err := w.adapter.GetBookTags(ctx, bookID)
@@ -201,19 +309,17 @@ func (w *mysqlWrapper) GetBookTags(ctx context.Context, bookID int64) ([]Tag, er
 	items := make([]Tag, len(res))
 	for i, v := range res {
 		items[i] = Tag{
-			ID: v.ID,
-
-			Name: v.Name,
-
+			ID:        v.ID,
+			Name:      v.Name,
 			CreatedAt: v.CreatedAt,
-
 			UpdatedAt: v.UpdatedAt,
 		}
 	}
 	return items, nil
 }
 
-func (w *mysqlWrapper) GetBooksByAuthor(ctx context.Context, author string) ([]Book, error) {
+func (w *mysqlWrapper) GetBooksByAuthor(ctx context.Context, author string) (_ []Book, err error) {
+	defer func() { err = classifyMySQLError(err) }()
 	/* --- Auto-Loop for Bulk Insert on Non-Postgres --- */
 
 	res, err := w.adapter.GetBooksByAuthor(ctx, author)
@@ -225,23 +331,19 @@ func (w *mysqlWrapper) GetBooksByAuthor(ctx context.Context, author string) ([]B
 	items := make([]Book, len(res))
 	for i, v := range res {
 		items[i] = Book{
-			ID: v.ID,
-
-			Title: v.Title,
-
-			Author: v.Author,
-
+			ID:          v.ID,
+			Title:       v.Title,
+			Author:      v.Author,
 			Description: v.Description,
-
-			CreatedAt: v.CreatedAt,
-
-			UpdatedAt: v.UpdatedAt,
+			CreatedAt:   v.CreatedAt,
+			UpdatedAt:   v.UpdatedAt,
 		}
 	}
 	return items, nil
 }
 
-func (w *mysqlWrapper) GetTag(ctx context.Context, id int64) (Tag, error) {
+func (w *mysqlWrapper) GetTag(ctx context.Context, id int64) (_ Tag, err error) {
+	defer func() { err = classifyMySQLError(err) }()
 	/* --- Auto-Loop for Bulk Insert on Non-Postgres --- */
 
 	res, err := w.adapter.GetTag(ctx, id)
@@ -257,23 +359,21 @@ func (w *mysqlWrapper) GetTag(ctx context.Context, id int64) (Tag, error) {
 	// Convert Single Domain Struct
 
 	return Tag{
-		ID: res.ID,
-
-		Name: res.Name,
-
+		ID:        res.ID,
+		Name:      res.Name,
 		CreatedAt: res.CreatedAt,
-
 		UpdatedAt: res.UpdatedAt,
 	}, nil
 }
 
-func (w *mysqlWrapper) GetTagByID(ctx context.Context, id int64) (Tag, error) {
+func (w *mysqlWrapper) GetTagByID(ctx context.Context, id int64) (_ Tag, err error) {
+	defer func() { err = classifyMySQLError(err) }()
 	/* --- Auto-Loop for Bulk Insert on Non-Postgres --- */
 
 	query := "SELECT `id`, `name`, `created_at`, `updated_at` FROM tags WHERE id = ?"
 	row := w.adapter.DBTX().QueryRowContext(ctx, query, id)
 	var res mysqldb.Tag
-	err := row.Scan(
+	err = row.Scan(
 
 		&res.ID,
 
@@ -293,17 +393,15 @@ func (w *mysqlWrapper) GetTagByID(ctx context.Context, id int64) (Tag, error) {
 	// Convert to Domain Struct
 
 	return Tag{
-		ID: res.ID,
-
-		Name: res.Name,
-
+		ID:        res.ID,
+		Name:      res.Name,
 		CreatedAt: res.CreatedAt,
-
 		UpdatedAt: res.UpdatedAt,
 	}, nil
 }
 
-func (w *mysqlWrapper) ListBooks(ctx context.Context) ([]Book, error) {
+func (w *mysqlWrapper) ListBooks(ctx context.Context) (_ []Book, err error) {
+	defer func() { err = classifyMySQLError(err) }()
 	/* --- Auto-Loop for Bulk Insert on Non-Postgres --- */
 
 	res, err := w.adapter.ListBooks(ctx)
@@ -315,23 +413,19 @@ func (w *mysqlWrapper) ListBooks(ctx context.Context) ([]Book, error) {
 	items := make([]Book, len(res))
 	for i, v := range res {
 		items[i] = Book{
-			ID: v.ID,
-
-			Title: v.Title,
-
-			Author: v.Author,
-
+			ID:          v.ID,
+			Title:       v.Title,
+			Author:      v.Author,
 			Description: v.Description,
-
-			CreatedAt: v.CreatedAt,
-
-			UpdatedAt: v.UpdatedAt,
+			CreatedAt:   v.CreatedAt,
+			UpdatedAt:   v.UpdatedAt,
 		}
 	}
 	return items, nil
 }
 
-func (w *mysqlWrapper) UpdateBook(ctx context.Context, arg UpdateBookParams) (Book, error) {
+func (w *mysqlWrapper) UpdateBook(ctx context.Context, arg UpdateBookParams) (_ Book, err error) {
+	defer func() { err = classifyMySQLError(err) }()
 	/* --- Auto-Loop for Bulk Insert on Non-Postgres --- */
 
 	// MySQL does not support RETURNING for UPDATEs.
@@ -365,3 +459,120 @@ func (w *mysqlWrapper) WithTx(tx *sql.Tx) Querier {
 func (w *mysqlWrapper) DB() *sql.DB {
 	return w.adapter.DB()
 }
+
+func (w *mysqlWrapper) WithDBTX(db DBTX) Querier {
+	return &mysqlWrapper{adapter: w.adapter.WithDBTX(db)}
+}
+
+// mysqlTxSavepoints numbers the savepoints of BeginTx calls nested in a transaction,
+// so that every level of nesting releases or rolls back its own savepoint.
+var mysqlTxSavepoints atomic.Uint64
+
+// mysqlTxWrapper is the TxQuerier returned by BeginTx.
+type mysqlTxWrapper struct {
+	*mysqlWrapper
+	ctx       context.Context // context of BeginTx, as Commit and Rollback take none
+	tx        *sql.Tx
+	savepoint string // nested BeginTx: Commit and Rollback apply to this savepoint of tx
+	done      bool
+}
+
+func (w *mysqlWrapper) BeginTx(ctx context.Context, opts *sql.TxOptions) (TxQuerier, error) {
+	if tx := w.currentTx(); tx != nil {
+		name := fmt.Sprintf("sqlc_multi_db_begin_%d", mysqlTxSavepoints.Add(1))
+
+		if _, err := tx.ExecContext(ctx, "SAVEPOINT "+name); err != nil {
+			return nil, w.classifyError(err)
+		}
+
+		return &mysqlTxWrapper{mysqlWrapper: w, ctx: ctx, tx: tx, savepoint: name}, nil
+	}
+
+	tx, err := w.beginTx(ctx, w.txOptions(opts))
+	if err != nil {
+		return nil, w.classifyError(err)
+	}
+
+	return &mysqlTxWrapper{mysqlWrapper: &mysqlWrapper{adapter: w.adapter.WithTx(tx)}, ctx: ctx, tx: tx}, nil
+}
+
+func (t *mysqlTxWrapper) Commit() error {
+	if t.savepoint == "" {
+		return t.classifyError(t.tx.Commit())
+	}
+
+	if t.done {
+		return sql.ErrTxDone
+	}
+
+	t.done = true
+
+	_, err := t.tx.ExecContext(t.ctx, "RELEASE SAVEPOINT "+t.savepoint)
+
+	return t.classifyError(err)
+}
+
+func (t *mysqlTxWrapper) Rollback() error {
+	if t.savepoint == "" {
+		return t.tx.Rollback()
+	}
+
+	if t.done {
+		return sql.ErrTxDone
+	}
+
+	t.done = true
+
+	if _, err := t.tx.ExecContext(t.ctx, "ROLLBACK TO SAVEPOINT "+t.savepoint); err != nil {
+		return err
+	}
+
+	_, err := t.tx.ExecContext(t.ctx, "RELEASE SAVEPOINT "+t.savepoint)
+
+	return err
+}
+
+// beginTx begins a transaction on the executor set with WithDBTX when it can begin one,
+// like a *sql.Conn, and on DB() otherwise.
+func (w *mysqlWrapper) beginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+	if conn, ok := w.adapter.DBTX().(interface {
+		BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+	}); ok {
+		return conn.BeginTx(ctx, opts)
+	}
+
+	return w.adapter.DB().BeginTx(ctx, opts)
+}
+
+// currentTx returns the transaction the wrapper is scoped to with WithTx, if any.
+func (w *mysqlWrapper) currentTx() *sql.Tx {
+	tx, _ := w.adapter.DBTX().(*sql.Tx)
+
+	return tx
+}
+
+// txOptions maps opts to the isolation levels MySQL supports.
+func (w *mysqlWrapper) txOptions(opts *sql.TxOptions) *sql.TxOptions {
+	if opts == nil {
+		return nil
+	}
+
+	mapped := *opts
+
+	switch opts.Isolation {
+	case sql.LevelWriteCommitted:
+		mapped.Isolation = sql.LevelReadCommitted
+	case sql.LevelSnapshot:
+		// InnoDB REPEATABLE READ reads from a snapshot taken at the first query.
+		mapped.Isolation = sql.LevelRepeatableRead
+	case sql.LevelLinearizable:
+		mapped.Isolation = sql.LevelSerializable
+	}
+
+	return &mapped
+}
+
+// classifyError classifies an error returned while beginning or committing a transaction.
+func (w *mysqlWrapper) classifyError(err error) error {
+	return classifyMySQLError(err)
+}
@@ -8,6 +8,8 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
+	"sync/atomic"
 
 	"github.com/kalbasit/sqlc-multi-db/example/pkg/database/postgresdb"
 )
@@ -17,7 +19,88 @@ type postgresWrapper struct {
 	adapter *postgresdb.Adapter
 }
 
-func (w *postgresWrapper) AddBookTag(ctx context.Context, arg AddBookTagParams) error {
+// NewPostgresQuerier returns a Querier running queries on the Postgres database db.
+func NewPostgresQuerier(db *sql.DB) Querier {
+	return &postgresWrapper{adapter: postgresdb.NewAdapter(db)}
+}
+
+// NewPostgresQuerierFromAdapter returns a Querier running queries through an
+// existing postgresdb.Adapter, e.g. one scoped to a transaction with WithTx.
+func NewPostgresQuerierFromAdapter(adapter *postgresdb.Adapter) Querier {
+	return &postgresWrapper{adapter: adapter}
+}
+
+// postgresBulkSavepoints numbers the savepoints of runBulk calls inside an existing
+// transaction, so that bulk calls nested in BeginTx or RunInTx savepoints never share a name.
+var postgresBulkSavepoints atomic.Uint64
+
+// runBulk runs a bulk operation made of several statements atomically. Outside a
+// transaction it begins one (see beginTx); inside WithTx it uses a savepoint so a failed
+// bulk call is rolled back without aborting the caller's transaction.
+func (w *postgresWrapper) runBulk(ctx context.Context, fn func(a *postgresdb.Adapter) error) error {
+	if tx, ok := w.adapter.DBTX().(*sql.Tx); ok {
+		name := fmt.Sprintf("sqlc_multi_db_bulk_%d", postgresBulkSavepoints.Add(1))
+
+		if _, err := tx.ExecContext(ctx, "SAVEPOINT "+name); err != nil {
+			return err
+		}
+
+		if err := fn(w.adapter); err != nil {
+			_, _ = tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+name)
+			_, _ = tx.ExecContext(ctx, "RELEASE SAVEPOINT "+name)
+
+			return err
+		}
+
+		_, err := tx.ExecContext(ctx, "RELEASE SAVEPOINT "+name)
+
+		return err
+	}
+
+	tx, err := w.beginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := fn(w.adapter.WithTx(tx)); err != nil {
+		_ = tx.Rollback()
+
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// classifyPostgresError wraps a Postgres driver error with the matching
+// classification sentinel, such as ErrDuplicateKey. Other errors are returned unchanged.
+// No driver is imported: any driver of the engine is recognized.
+func classifyPostgresError(err error) error {
+	// Implemented by both pgx's *pgconn.PgError and lib/pq's *pq.Error.
+	var pgErr interface{ SQLState() string }
+	if !errors.As(err, &pgErr) {
+		return err
+	}
+
+	switch pgErr.SQLState() {
+	case "23505": // unique_violation
+		return classify(ErrDuplicateKey, err)
+	case "23503": // foreign_key_violation
+		return classify(ErrForeignKeyViolation, err)
+	case "23514": // check_violation
+		return classify(ErrCheckViolation, err)
+	case "23502": // not_null_violation
+		return classify(ErrNotNullViolation, err)
+	case "40P01": // deadlock_detected
+		return classify(ErrDeadlock, err)
+	case "40001": // serialization_failure
+		return classify(ErrSerialization, err)
+	}
+
+	return err
+}
+
+func (w *postgresWrapper) AddBookTag(ctx context.Context, arg AddBookTagParams) (err error) {
+	defer func() { err = classifyPostgresError(err) }()
 	/* --- Auto-Loop for Bulk Insert on Non-Postgres --- */
 
 	return w.adapter.AddBookTag(ctx, postgresdb.AddBookTagParams{
@@ -26,7 +109,8 @@ func (w *postgresWrapper) AddBookTag(ctx context.Context, arg AddBookTagParams)
 	})
 }
 
-func (w *postgresWrapper) AddBookTags(ctx context.Context, arg AddBookTagsParams) error {
+func (w *postgresWrapper) AddBookTags(ctx context.Context, arg AddBookTagsParams) (err error) {
+	defer func() { err = classifyPostgresError(err) }()
 	/* --- Auto-Loop for Bulk Insert on Non-Postgres --- */
 
 	if len(arg.TagIds) != len(arg.BookIds) {
@@ -39,7 +123,8 @@ func (w *postgresWrapper) AddBookTags(ctx context.Context, arg AddBookTagsParams
 	})
 }
 
-func (w *postgresWrapper) CreateBook(ctx context.Context, arg CreateBookParams) (Book, error) {
+func (w *postgresWrapper) CreateBook(ctx context.Context, arg CreateBookParams) (_ Book, err error) {
+	defer func() { err = classifyPostgresError(err) }()
 	/* --- Auto-Loop for Bulk Insert on Non-Postgres --- */
 
 	res, err := w.adapter.CreateBook(ctx, postgresdb.CreateBookParams{
@@ -59,21 +144,17 @@ func (w *postgresWrapper) CreateBook(ctx context.Context, arg CreateBookParams)
 	// Convert Single Domain Struct
 
 	return Book{
-		ID: res.ID,
-
-		Title: res.Title,
-
-		Author: res.Author,
-
+		ID:          res.ID,
+		Title:       res.Title,
+		Author:      res.Author,
 		Description: res.Description,
-
-		CreatedAt: res.CreatedAt,
-
-		UpdatedAt: res.UpdatedAt,
+		CreatedAt:   res.CreatedAt,
+		UpdatedAt:   res.UpdatedAt,
 	}, nil
 }
 
-func (w *postgresWrapper) CreateTag(ctx context.Context, name string) (Tag, error) {
+func (w *postgresWrapper) CreateTag(ctx context.Context, name string) (_ Tag, err error) {
+	defer func() { err = classifyPostgresError(err) }()
 	/* --- Auto-Loop for Bulk Insert on Non-Postgres --- */
 
 	res, err := w.adapter.CreateTag(ctx, name)
@@ -89,23 +170,22 @@ func (w *postgresWrapper) CreateTag(ctx context.Context, name string) (Tag, erro
 	// Convert Single Domain Struct
 
 	return Tag{
-		ID: res.ID,
-
-		Name: res.Name,
-
+		ID:        res.ID,
+		Name:      res.Name,
 		CreatedAt: res.CreatedAt,
-
 		UpdatedAt: res.UpdatedAt,
 	}, nil
 }
 
-func (w *postgresWrapper) DeleteBook(ctx context.Context, id int64) error {
+func (w *postgresWrapper) DeleteBook(ctx context.Context, id int64) (err error) {
+	defer func() { err = classifyPostgresError(err) }()
 	/* --- Auto-Loop for Bulk Insert on Non-Postgres --- */
 
 	return w.adapter.DeleteBook(ctx, id)
 }
 
-func (w *postgresWrapper) GetBook(ctx context.Context, id int64) (Book, error) {
+func (w *postgresWrapper) GetBook(ctx context.Context, id int64) (_ Book, err error) {
+	defer func() { err = classifyPostgresError(err) }()
 	/* --- Auto-Loop for Bulk Insert on Non-Postgres --- */
 
 	res, err := w.adapter.GetBook(ctx, id)
@@ -121,27 +201,23 @@ func (w *postgresWrapper) GetBook(ctx context.Context, id int64) (Book, error) {
 	// Convert Single Domain Struct
 
 	return Book{
-		ID: res.ID,
-
-		Title: res.Title,
-
-		Author: res.Author,
-
+		ID:          res.ID,
+		Title:       res.Title,
+		Author:      res.Author,
 		Description: res.Description,
-
-		CreatedAt: res.CreatedAt,
-
-		UpdatedAt: res.UpdatedAt,
+		CreatedAt:   res.CreatedAt,
+		UpdatedAt:   res.UpdatedAt,
 	}, nil
 }
 
-func (w *postgresWrapper) GetBookByID(ctx context.Context, id int64) (Book, error) {
+func (w *postgresWrapper) GetBookByID(ctx context.Context, id int64) (_ Book, err error) {
+	defer func() { err = classifyPostgresError(err) }()
 	/* --- Auto-Loop for Bulk Insert on Non-Postgres --- */
 
 	query := "SELECT \"id\", \"title\", \"author\", \"description\", \"created_at\", \"updated_at\" FROM books WHERE id = $1"
 	row := w.adapter.DBTX().QueryRowContext(ctx, query, id)
 	var res postgresdb.Book
-	err := row.Scan(
+	err = row.Scan(
 
 		&res.ID,
 
@@ -165,21 +241,17 @@ func (w *postgresWrapper) GetBookByID(ctx context.Context, id int64) (Book, erro
 	// Convert to Domain Struct
 
 	return Book{
-		ID: res.ID,
-
-		Title: res.Title,
-
-		Author: res.Author,
-
+		ID:          res.ID,
+		Title:       res.Title,
+		Author:      res.Author,
 		Description: res.Description,
-
-		CreatedAt: res.CreatedAt,
-
-		UpdatedAt: res.UpdatedAt,
+		CreatedAt:   res.CreatedAt,
+		UpdatedAt:   res.UpdatedAt,
 	}, nil
 }
 
-func (w *postgresWrapper) GetBookTags(ctx context.Context, bookID int64) ([]Tag, error) {
+func (w *postgresWrapper) GetBookTags(ctx context.Context, bookID int64) (_ []Tag, err error) {
+	defer func() { err = classifyPostgresError(err) }()
 	/* --- Auto-Loop for Bulk Insert on Non-Postgres --- */
 
 	res, err := w.adapter.GetBookTags(ctx, bookID)
@@ -191,19 +263,17 @@ func (w *postgresWrapper) GetBookTags(ctx context.Context, bookID int64) ([]Tag,
 	items := make([]Tag, len(res))
 	for i, v := range res {
 		items[i] = Tag{
-			ID: v.ID,
-
-			Name: v.Name,
-
+			ID:        v.ID,
+			Name:      v.Name,
 			CreatedAt: v.CreatedAt,
-
 			UpdatedAt: v.UpdatedAt,
 		}
 	}
 	return items, nil
 }
 
-func (w *postgresWrapper) GetBooksByAuthor(ctx context.Context, author string) ([]Book, error) {
+func (w *postgresWrapper) GetBooksByAuthor(ctx context.Context, author string) (_ []Book, err error) {
+	defer func() { err = classifyPostgresError(err) }()
 	/* --- Auto-Loop for Bulk Insert on Non-Postgres --- */
 
 	res, err := w.adapter.GetBooksByAuthor(ctx, author)
@@ -215,23 +285,19 @@ func (w *postgresWrapper) GetBooksByAuthor(ctx context.Context, author string) (
 	items := make([]Book, len(res))
 	for i, v := range res {
 		items[i] = Book{
-			ID: v.ID,
-
-			Title: v.Title,
-
-			Author: v.Author,
-
+			ID:          v.ID,
+			Title:       v.Title,
+			Author:      v.Author,
 			Description: v.Description,
-
-			CreatedAt: v.CreatedAt,
-
-			UpdatedAt: v.UpdatedAt,
+			CreatedAt:   v.CreatedAt,
+			UpdatedAt:   v.UpdatedAt,
 		}
 	}
 	return items, nil
 }
 
-func (w *postgresWrapper) GetTag(ctx context.Context, id int64) (Tag, error) {
+func (w *postgresWrapper) GetTag(ctx context.Context, id int64) (_ Tag, err error) {
+	defer func() { err = classifyPostgresError(err) }()
 	/* --- Auto-Loop for Bulk Insert on Non-Postgres --- */
 
 	res, err := w.adapter.GetTag(ctx, id)
@@ -247,23 +313,21 @@ func (w *postgresWrapper) GetTag(ctx context.Context, id int64) (Tag, error) {
 	// Convert Single Domain Struct
 
 	return Tag{
-		ID: res.ID,
-
-		Name: res.Name,
-
+		ID:        res.ID,
+		Name:      res.Name,
 		CreatedAt: res.CreatedAt,
-
 		UpdatedAt: res.UpdatedAt,
 	}, nil
 }
 
-func (w *postgresWrapper) GetTagByID(ctx context.Context, id int64) (Tag, error) {
+func (w *postgresWrapper) GetTagByID(ctx context.Context, id int64) (_ Tag, err error) {
+	defer func() { err = classifyPostgresError(err) }()
 	/* --- Auto-Loop for Bulk Insert on Non-Postgres --- */
 
 	query := "SELECT \"id\", \"name\", \"created_at\", \"updated_at\" FROM tags WHERE id = $1"
 	row := w.adapter.DBTX().QueryRowContext(ctx, query, id)
 	var res postgresdb.Tag
-	err := row.Scan(
+	err = row.Scan(
 
 		&res.ID,
 
@@ -283,17 +347,15 @@ func (w *postgresWrapper) GetTagByID(ctx context.Context, id int64) (Tag, error)
 	// Convert to Domain Struct
 
 	return Tag{
-		ID: res.ID,
-
-		Name: res.Name,
-
+		ID:        res.ID,
+		Name:      res.Name,
 		CreatedAt: res.CreatedAt,
-
 		UpdatedAt: res.UpdatedAt,
 	}, nil
 }
 
-func (w *postgresWrapper) ListBooks(ctx context.Context) ([]Book, error) {
+func (w *postgresWrapper) ListBooks(ctx context.Context) (_ []Book, err error) {
+	defer func() { err = classifyPostgresError(err) }()
 	/* --- Auto-Loop for Bulk Insert on Non-Postgres --- */
 
 	res, err := w.adapter.ListBooks(ctx)
@@ -305,23 +367,19 @@ func (w *postgresWrapper) ListBooks(ctx context.Context) ([]Book, error) {
 	items := make([]Book, len(res))
 	for i, v := range res {
 		items[i] = Book{
-			ID: v.ID,
-
-			Title: v.Title,
-
-			Author: v.Author,
-
+			ID:          v.ID,
+			Title:       v.Title,
+			Author:      v.Author,
 			Description: v.Description,
-
-			CreatedAt: v.CreatedAt,
-
-			UpdatedAt: v.UpdatedAt,
+			CreatedAt:   v.CreatedAt,
+			UpdatedAt:   v.UpdatedAt,
 		}
 	}
 	return items, nil
 }
 
-func (w *postgresWrapper) UpdateBook(ctx context.Context, arg UpdateBookParams) (Book, error) {
+func (w *postgresWrapper) UpdateBook(ctx context.Context, arg UpdateBookParams) (_ Book, err error) {
+	defer func() { err = classifyPostgresError(err) }()
 	/* --- Auto-Loop for Bulk Insert on Non-Postgres --- */
 
 	res, err := w.adapter.UpdateBook(ctx, postgresdb.UpdateBookParams{
@@ -342,17 +400,12 @@ func (w *postgresWrapper) UpdateBook(ctx context.Context, arg UpdateBookParams)
 	// Convert Single Domain Struct
 
 	return Book{
-		ID: res.ID,
-
-		Title: res.Title,
-
-		Author: res.Author,
-
+		ID:          res.ID,
+		Title:       res.Title,
+		Author:      res.Author,
 		Description: res.Description,
-
-		CreatedAt: res.CreatedAt,
-
-		UpdatedAt: res.UpdatedAt,
+		CreatedAt:   res.CreatedAt,
+		UpdatedAt:   res.UpdatedAt,
 	}, nil
 }
 
@@ -364,3 +417,120 @@ func (w *postgresWrapper) WithTx(tx *sql.Tx) Querier {
 func (w *postgresWrapper) DB() *sql.DB {
 	return w.adapter.DB()
 }
+
+func (w *postgresWrapper) WithDBTX(db DBTX) Querier {
+	return &postgresWrapper{adapter: w.adapter.WithDBTX(db)}
+}
+
+// postgresTxSavepoints numbers the savepoints of BeginTx calls nested in a transaction,
+// so that every level of nesting releases or rolls back its own savepoint.
+var postgresTxSavepoints atomic.Uint64
+
+// postgresTxWrapper is the TxQuerier returned by BeginTx.
+type postgresTxWrapper struct {
+	*postgresWrapper
+	ctx       context.Context // context of BeginTx, as Commit and Rollback take none
+	tx        *sql.Tx
+	savepoint string // nested BeginTx: Commit and Rollback apply to this savepoint of tx
+	done      bool
+}
+
+func (w *postgresWrapper) BeginTx(ctx context.Context, opts *sql.TxOptions) (TxQuerier, error) {
+	if tx := w.currentTx(); tx != nil {
+		name := fmt.Sprintf("sqlc_multi_db_begin_%d", postgresTxSavepoints.Add(1))
+
+		if _, err := tx.ExecContext(ctx, "SAVEPOINT "+name); err != nil {
+			return nil, w.classifyError(err)
+		}
+
+		return &postgresTxWrapper{postgresWrapper: w, ctx: ctx, tx: tx, savepoint: name}, nil
+	}
+
+	tx, err := w.beginTx(ctx, w.txOptions(opts))
+	if err != nil {
+		return nil, w.classifyError(err)
+	}
+
+	return &postgresTxWrapper{postgresWrapper: &postgresWrapper{adapter: w.adapter.WithTx(tx)}, ctx: ctx, tx: tx}, nil
+}
+
+func (t *postgresTxWrapper) Commit() error {
+	if t.savepoint == "" {
+		return t.classifyError(t.tx.Commit())
+	}
+
+	if t.done {
+		return sql.ErrTxDone
+	}
+
+	t.done = true
+
+	_, err := t.tx.ExecContext(t.ctx, "RELEASE SAVEPOINT "+t.savepoint)
+
+	return t.classifyError(err)
+}
+
+func (t *postgresTxWrapper) Rollback() error {
+	if t.savepoint == "" {
+		return t.tx.Rollback()
+	}
+
+	if t.done {
+		return sql.ErrTxDone
+	}
+
+	t.done = true
+
+	if _, err := t.tx.ExecContext(t.ctx, "ROLLBACK TO SAVEPOINT "+t.savepoint); err != nil {
+		return err
+	}
+
+	_, err := t.tx.ExecContext(t.ctx, "RELEASE SAVEPOINT "+t.savepoint)
+
+	return err
+}
+
+// beginTx begins a transaction on the executor set with WithDBTX when it can begin one,
+// like a *sql.Conn, and on DB() otherwise.
+func (w *postgresWrapper) beginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+	if conn, ok := w.adapter.DBTX().(interface {
+		BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+	}); ok {
+		return conn.BeginTx(ctx, opts)
+	}
+
+	return w.adapter.DB().BeginTx(ctx, opts)
+}
+
+// currentTx returns the transaction the wrapper is scoped to with WithTx, if any.
+func (w *postgresWrapper) currentTx() *sql.Tx {
+	tx, _ := w.adapter.DBTX().(*sql.Tx)
+
+	return tx
+}
+
+// txOptions maps opts to the isolation levels Postgres supports.
+func (w *postgresWrapper) txOptions(opts *sql.TxOptions) *sql.TxOptions {
+	if opts == nil {
+		return nil
+	}
+
+	mapped := *opts
+
+	switch opts.Isolation {
+	case sql.LevelWriteCommitted:
+		mapped.Isolation = sql.LevelReadCommitted
+	case sql.LevelSnapshot:
+		// PostgreSQL REPEATABLE READ reads from a snapshot taken at the first query.
+		mapped.Isolation = sql.LevelRepeatableRead
+	case sql.LevelLinearizable:
+		mapped.Isolation = sql.LevelSerializable
+	}
+
+	return &mapped
+}
+
+// classifyError classifies an error returned while beginning or committing a transaction.
+func (w *postgresWrapper) classifyError(err error) error {
+	return classifyPostgresError(err)
+}
@@ -6,6 +6,9 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
 
 	"github.com/kalbasit/sqlc-multi-db/example/pkg/database/sqlitedb"
 )
@@ -13,9 +16,110 @@ import (
 // sqliteWrapper wraps the sqlite adapter.
 type sqliteWrapper struct {
 	adapter *sqlitedb.Adapter
+	reader  *sqlitedb.Adapter // read-only pool for read-only queries; nil sends them to adapter
 }
 
-func (w *sqliteWrapper) AddBookTag(ctx context.Context, arg AddBookTagParams) error {
+// NewSQLiteQuerier returns a Querier running queries on the SQLite database db.
+func NewSQLiteQuerier(db *sql.DB) Querier {
+	return &sqliteWrapper{adapter: sqlitedb.NewAdapter(db)}
+}
+
+// NewSQLiteQuerierFromAdapter returns a Querier running queries through an
+// existing sqlitedb.Adapter, e.g. one scoped to a transaction with WithTx.
+func NewSQLiteQuerierFromAdapter(adapter *sqlitedb.Adapter) Querier {
+	return &sqliteWrapper{adapter: adapter}
+}
+
+// NewSQLiteQuerierWithReader returns a Querier that runs read-only queries on reader and
+// everything else, including transactions, on writer. Both must open the same database;
+// writer should be limited to a single connection and the database should use WAL so
+// that readers do not block the writer.
+func NewSQLiteQuerierWithReader(writer, reader *sql.DB) Querier {
+	return &sqliteWrapper{adapter: sqlitedb.NewAdapter(writer), reader: sqlitedb.NewAdapter(reader)}
+}
+
+// readAdapter returns the adapter running read-only queries.
+func (w *sqliteWrapper) readAdapter() *sqlitedb.Adapter {
+	if w.reader != nil {
+		return w.reader
+	}
+
+	return w.adapter
+}
+
+// sqliteBulkSavepoints numbers the savepoints of runBulk calls inside an existing
+// transaction, so that bulk calls nested in BeginTx or RunInTx savepoints never share a name.
+var sqliteBulkSavepoints atomic.Uint64
+
+// sqliteMaxPlaceholders is the number of bind parameters a single statement may use.
+const sqliteMaxPlaceholders = 32766
+
+// runBulk runs a bulk operation made of several statements atomically. Outside a
+// transaction it begins one (see beginTx); inside WithTx it uses a savepoint so a failed
+// bulk call is rolled back without aborting the caller's transaction.
+func (w *sqliteWrapper) runBulk(ctx context.Context, fn func(a *sqlitedb.Adapter) error) error {
+	if tx, ok := w.adapter.DBTX().(*sql.Tx); ok {
+		name := fmt.Sprintf("sqlc_multi_db_bulk_%d", sqliteBulkSavepoints.Add(1))
+
+		if _, err := tx.ExecContext(ctx, "SAVEPOINT "+name); err != nil {
+			return err
+		}
+
+		if err := fn(w.adapter); err != nil {
+			_, _ = tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+name)
+			_, _ = tx.ExecContext(ctx, "RELEASE SAVEPOINT "+name)
+
+			return err
+		}
+
+		_, err := tx.ExecContext(ctx, "RELEASE SAVEPOINT "+name)
+
+		return err
+	}
+
+	tx, err := w.beginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := fn(w.adapter.WithTx(tx)); err != nil {
+		_ = tx.Rollback()
+
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// classifySQLiteError wraps a SQLite driver error with the matching
+// classification sentinel, such as ErrDuplicateKey. Other errors are returned unchanged.
+// No driver is imported: any driver of the engine is recognized.
+func classifySQLiteError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	// The messages come from SQLite itself, so they are the same with every driver.
+	msg := err.Error()
+
+	switch {
+	case strings.Contains(msg, "UNIQUE constraint failed"):
+		return classify(ErrDuplicateKey, err)
+	case strings.Contains(msg, "FOREIGN KEY constraint failed"):
+		return classify(ErrForeignKeyViolation, err)
+	case strings.Contains(msg, "CHECK constraint failed"):
+		return classify(ErrCheckViolation, err)
+	case strings.Contains(msg, "NOT NULL constraint failed"):
+		return classify(ErrNotNullViolation, err)
+	case strings.Contains(msg, "database is locked"), strings.Contains(msg, "database table is locked"):
+		return classify(ErrDeadlock, err)
+	}
+
+	return err
+}
+
+func (w *sqliteWrapper) AddBookTag(ctx context.Context, arg AddBookTagParams) (err error) {
+	defer func() { err = classifySQLiteError(err) }()
 	/* --- Auto-Loop for Bulk Insert on Non-Postgres --- */
 
 	return w.adapter.AddBookTag(ctx, sqlitedb.AddBookTagParams{
@@ -24,28 +128,47 @@ func (w *sqliteWrapper) AddBookTag(ctx context.Context, arg AddBookTagParams) er
 	})
 }
 
-func (w *sqliteWrapper) AddBookTags(ctx context.Context, arg AddBookTagsParams) error {
+func (w *sqliteWrapper) AddBookTags(ctx context.Context, arg AddBookTagsParams) (err error) {
+	defer func() { err = classifySQLiteError(err) }()
 	/* --- Auto-Loop for Bulk Insert on Non-Postgres --- */
 
 	if len(arg.TagIds) != len(arg.BookIds) {
 		return ErrMismatchedSlices
 	}
-	for i, v := range arg.BookIds {
-		_ = i
-		err := w.adapter.AddBookTag(ctx, sqlitedb.AddBookTagParams{
-			BookID: v,
-
-			TagID: arg.TagIds[i],
-		},
-		)
-		if err != nil {
-			return err
+	return w.runBulk(ctx, func(a *sqlitedb.Adapter) error {
+		// AddBookTag is repeated as a multi-row INSERT, chunked to stay
+		// within the engine's placeholder limit.
+		const rowPlaceholders = 2
+
+		chunkSize := sqliteMaxPlaceholders / rowPlaceholders
+		for start := 0; start < len(arg.BookIds); start += chunkSize {
+			if err := ctx.Err(); err != nil {
+				return &BulkError{Method: "AddBookTags", Index: start, Err: err}
+			}
+
+			end := min(start+chunkSize, len(arg.BookIds))
+			args := make([]any, 0, (end-start)*rowPlaceholders)
+			for i := start; i < end; i++ {
+				v := arg.BookIds[i]
+				row := sqlitedb.AddBookTagParams{
+					BookID: v,
+					TagID:  arg.TagIds[i],
+				}
+				args = append(args, row.BookID, row.TagID)
+			}
+
+			query := "INSERT INTO book_tags (\"book_id\", \"tag_id\") VALUES " + "(?, ?)" +
+				strings.Repeat(", (?, ?)", end-start-1)
+			if _, err := a.DBTX().ExecContext(ctx, query, args...); err != nil {
+				return &BulkError{Method: "AddBookTags", Index: start, Err: err}
+			}
 		}
-	}
-	return nil
+		return nil
+	})
 }
 
-func (w *sqliteWrapper) CreateBook(ctx context.Context, arg CreateBookParams) (Book, error) {
+func (w *sqliteWrapper) CreateBook(ctx context.Context, arg CreateBookParams) (_ Book, err error) {
+	defer func() { err = classifySQLiteError(err) }()
 	/* --- Auto-Loop for Bulk Insert on Non-Postgres --- */
 
 	res, err := w.adapter.CreateBook(ctx, sqlitedb.CreateBookParams{
@@ -65,21 +188,17 @@ func (w *sqliteWrapper) CreateBook(ctx context.Context, arg CreateBookParams) (B
 	// Convert Single Domain Struct
 
 	return Book{
-		ID: res.ID,
-
-		Title: res.Title,
-
-		Author: res.Author,
-
+		ID:          res.ID,
+		Title:       res.Title,
+		Author:      res.Author,
 		Description: res.Description,
-
-		CreatedAt: res.CreatedAt,
-
-		UpdatedAt: res.UpdatedAt,
+		CreatedAt:   res.CreatedAt,
+		UpdatedAt:   res.UpdatedAt,
 	}, nil
 }
 
-func (w *sqliteWrapper) CreateTag(ctx context.Context, name string) (Tag, error) {
+func (w *sqliteWrapper) CreateTag(ctx context.Context, name string) (_ Tag, err error) {
+	defer func() { err = classifySQLiteError(err) }()
 	/* --- Auto-Loop for Bulk Insert on Non-Postgres --- */
 
 	res, err := w.adapter.CreateTag(ctx, name)
@@ -95,26 +214,25 @@ func (w *sqliteWrapper) CreateTag(ctx context.Context, name string) (Tag, error)
 	// Convert Single Domain Struct
 
 	return Tag{
-		ID: res.ID,
-
-		Name: res.Name,
-
+		ID:        res.ID,
+		Name:      res.Name,
 		CreatedAt: res.CreatedAt,
-
 		UpdatedAt: res.UpdatedAt,
 	}, nil
 }
 
-func (w *sqliteWrapper) DeleteBook(ctx context.Context, id int64) error {
+func (w *sqliteWrapper) DeleteBook(ctx context.Context, id int64) (err error) {
+	defer func() { err = classifySQLiteError(err) }()
 	/* --- Auto-Loop for Bulk Insert on Non-Postgres --- */
 
 	return w.adapter.DeleteBook(ctx, id)
 }
 
-func (w *sqliteWrapper) GetBook(ctx context.Context, id int64) (Book, error) {
+func (w *sqliteWrapper) GetBook(ctx context.Context, id int64) (_ Book, err error) {
+	defer func() { err = classifySQLiteError(err) }()
 	/* --- Auto-Loop for Bulk Insert on Non-Postgres --- */
 
-	res, err := w.adapter.GetBook(ctx, id)
+	res, err := w.readAdapter().GetBook(ctx, id)
 	if err != nil {
 
 		if errors.Is(err, sql.ErrNoRows) {
@@ -127,27 +245,23 @@ func (w *sqliteWrapper) GetBook(ctx context.Context, id int64) (Book, error) {
 	// Convert Single Domain Struct
 
 	return Book{
-		ID: res.ID,
-
-		Title: res.Title,
-
-		Author: res.Author,
-
+		ID:          res.ID,
+		Title:       res.Title,
+		Author:      res.Author,
 		Description: res.Description,
-
-		CreatedAt: res.CreatedAt,
-
-		UpdatedAt: res.UpdatedAt,
+		CreatedAt:   res.CreatedAt,
+		UpdatedAt:   res.UpdatedAt,
 	}, nil
 }
 
-func (w *sqliteWrapper) GetBookByID(ctx context.Context, id int64) (Book, error) {
+func (w *sqliteWrapper) GetBookByID(ctx context.Context, id int64) (_ Book, err error) {
+	defer func() { err = classifySQLiteError(err) }()
 	/* --- Auto-Loop for Bulk Insert on Non-Postgres --- */
 
 	query := "SELECT \"id\", \"title\", \"author\", \"description\", \"created_at\", \"updated_at\" FROM books WHERE id = ?"
 	row := w.adapter.DBTX().QueryRowContext(ctx, query, id)
 	var res sqlitedb.Book
-	err := row.Scan(
+	err = row.Scan(
 
 		&res.ID,
 
@@ -171,24 +285,20 @@ func (w *sqliteWrapper) GetBookByID(ctx context.Context, id int64) (Book, error)
 	// Convert to Domain Struct
 
 	return Book{
-		ID: res.ID,
-
-		Title: res.Title,
-
-		Author: res.Author,
-
+		ID:          res.ID,
+		Title:       res.Title,
+		Author:      res.Author,
 		Description: res.Description,
-
-		CreatedAt: res.CreatedAt,
-
-		UpdatedAt: res.UpdatedAt,
+		CreatedAt:   res.CreatedAt,
+		UpdatedAt:   res.UpdatedAt,
 	}, nil
 }
 
-func (w *sqliteWrapper) GetBookTags(ctx context.Context, bookID int64) ([]Tag, error) {
+func (w *sqliteWrapper) GetBookTags(ctx context.Context, bookID int64) (_ []Tag, err error) {
+	defer func() { err = classifySQLiteError(err) }()
 	/* --- Auto-Loop for Bulk Insert on Non-Postgres --- */
 
-	res, err := w.adapter.GetBookTags(ctx, bookID)
+	res, err := w.readAdapter().GetBookTags(ctx, bookID)
 	if err != nil {
 		return nil, err
 	}
@@ -197,22 +307,20 @@ func (w *sqliteWrapper) GetBookTags(ctx context.Context, bookID int64) ([]Tag, e
 	items := make([]Tag, len(res))
 	for i, v := range res {
 		items[i] = Tag{
-			ID: v.ID,
-
-			Name: v.Name,
-
+			ID:        v.ID,
+			Name:      v.Name,
 			CreatedAt: v.CreatedAt,
-
 			UpdatedAt: v.UpdatedAt,
 		}
 	}
 	return items, nil
 }
 
-func (w *sqliteWrapper) GetBooksByAuthor(ctx context.Context, author string) ([]Book, error) {
+func (w *sqliteWrapper) GetBooksByAuthor(ctx context.Context, author string) (_ []Book, err error) {
+	defer func() { err = classifySQLiteError(err) }()
 	/* --- Auto-Loop for Bulk Insert on Non-Postgres --- */
 
-	res, err := w.adapter.GetBooksByAuthor(ctx, author)
+	res, err := w.readAdapter().GetBooksByAuthor(ctx, author)
 	if err != nil {
 		return nil, err
 	}
@@ -221,26 +329,22 @@ func (w *sqliteWrapper) GetBooksByAuthor(ctx context.Context, author string) ([]
 	items := make([]Book, len(res))
 	for i, v := range res {
 		items[i] = Book{
-			ID: v.ID,
-
-			Title: v.Title,
-
-			Author: v.Author,
-
+			ID:          v.ID,
+			Title:       v.Title,
+			Author:      v.Author,
 			Description: v.Description,
-
-			CreatedAt: v.CreatedAt,
-
-			UpdatedAt: v.UpdatedAt,
+			CreatedAt:   v.CreatedAt,
+			UpdatedAt:   v.UpdatedAt,
 		}
 	}
 	return items, nil
 }
 
-func (w *sqliteWrapper) GetTag(ctx context.Context, id int64) (Tag, error) {
+func (w *sqliteWrapper) GetTag(ctx context.Context, id int64) (_ Tag, err error) {
+	defer func() { err = classifySQLiteError(err) }()
 	/* --- Auto-Loop for Bulk Insert on Non-Postgres --- */
 
-	res, err := w.adapter.GetTag(ctx, id)
+	res, err := w.readAdapter().GetTag(ctx, id)
 	if err != nil {
 
 		if errors.Is(err, sql.ErrNoRows) {
@@ -253,23 +357,21 @@ func (w *sqliteWrapper) GetTag(ctx context.Context, id int64) (Tag, error) {
 	// Convert Single Domain Struct
 
 	return Tag{
-		ID: res.ID,
-
-		Name: res.Name,
-
+		ID:        res.ID,
+		Name:      res.Name,
 		CreatedAt: res.CreatedAt,
-
 		UpdatedAt: res.UpdatedAt,
 	}, nil
 }
 
-func (w *sqliteWrapper) GetTagByID(ctx context.Context, id int64) (Tag, error) {
+func (w *sqliteWrapper) GetTagByID(ctx context.Context, id int64) (_ Tag, err error) {
+	defer func() { err = classifySQLiteError(err) }()
 	/* --- Auto-Loop for Bulk Insert on Non-Postgres --- */
 
 	query := "SELECT \"id\", \"name\", \"created_at\", \"updated_at\" FROM tags WHERE id = ?"
 	row := w.adapter.DBTX().QueryRowContext(ctx, query, id)
 	var res sqlitedb.Tag
-	err := row.Scan(
+	err = row.Scan(
 
 		&res.ID,
 
@@ -289,20 +391,18 @@ func (w *sqliteWrapper) GetTagByID(ctx context.Context, id int64) (Tag, error) {
 	// Convert to Domain Struct
 
 	return Tag{
-		ID: res.ID,
-
-		Name: res.Name,
-
+		ID:        res.ID,
+		Name:      res.Name,
 		CreatedAt: res.CreatedAt,
-
 		UpdatedAt: res.UpdatedAt,
 	}, nil
 }
 
-func (w *sqliteWrapper) ListBooks(ctx context.Context) ([]Book, error) {
+func (w *sqliteWrapper) ListBooks(ctx context.Context) (_ []Book, err error) {
+	defer func() { err = classifySQLiteError(err) }()
 	/* --- Auto-Loop for Bulk Insert on Non-Postgres --- */
 
-	res, err := w.adapter.ListBooks(ctx)
+	res, err := w.readAdapter().ListBooks(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -311,23 +411,19 @@ func (w *sqliteWrapper) ListBooks(ctx context.Context) ([]Book, error) {
 	items := make([]Book, len(res))
 	for i, v := range res {
 		items[i] = Book{
-			ID: v.ID,
-
-			Title: v.Title,
-
-			Author: v.Author,
-
+			ID:          v.ID,
+			Title:       v.Title,
+			Author:      v.Author,
 			Description: v.Description,
-
-			CreatedAt: v.CreatedAt,
-
-			UpdatedAt: v.UpdatedAt,
+			CreatedAt:   v.CreatedAt,
+			UpdatedAt:   v.UpdatedAt,
 		}
 	}
 	return items, nil
 }
 
-func (w *sqliteWrapper) UpdateBook(ctx context.Context, arg UpdateBookParams) (Book, error) {
+func (w *sqliteWrapper) UpdateBook(ctx context.Context, arg UpdateBookParams) (_ Book, err error) {
+	defer func() { err = classifySQLiteError(err) }()
 	/* --- Auto-Loop for Bulk Insert on Non-Postgres --- */
 
 	res, err := w.adapter.UpdateBook(ctx, sqlitedb.UpdateBookParams{
@@ -348,20 +444,17 @@ func (w *sqliteWrapper) UpdateBook(ctx context.Context, arg UpdateBookParams) (B
 	// Convert Single Domain Struct
 
 	return Book{
-		ID: res.ID,
-
-		Title: res.Title,
-
-		Author: res.Author,
-
+		ID:          res.ID,
+		Title:       res.Title,
+		Author:      res.Author,
 		Description: res.Description,
-
-		CreatedAt: res.CreatedAt,
-
-		UpdatedAt: res.UpdatedAt,
+		CreatedAt:   res.CreatedAt,
+		UpdatedAt:   res.UpdatedAt,
 	}, nil
 }
 
+// WithTx scopes every query to tx, including read-only ones that would otherwise use the
+// reader pool.
 func (w *sqliteWrapper) WithTx(tx *sql.Tx) Querier {
 	res := w.adapter.WithTx(tx)
 	return &sqliteWrapper{adapter: res}
@@ -370,3 +463,109 @@ func (w *sqliteWrapper) WithTx(tx *sql.Tx) Querier {
 func (w *sqliteWrapper) DB() *sql.DB {
 	return w.adapter.DB()
 }
+
+func (w *sqliteWrapper) WithDBTX(db DBTX) Querier {
+	return &sqliteWrapper{adapter: w.adapter.WithDBTX(db)}
+}
+
+// sqliteTxSavepoints numbers the savepoints of BeginTx calls nested in a transaction,
+// so that every level of nesting releases or rolls back its own savepoint.
+var sqliteTxSavepoints atomic.Uint64
+
+// sqliteTxWrapper is the TxQuerier returned by BeginTx.
+type sqliteTxWrapper struct {
+	*sqliteWrapper
+	ctx       context.Context // context of BeginTx, as Commit and Rollback take none
+	tx        *sql.Tx
+	savepoint string // nested BeginTx: Commit and Rollback apply to this savepoint of tx
+	done      bool
+}
+
+func (w *sqliteWrapper) BeginTx(ctx context.Context, opts *sql.TxOptions) (TxQuerier, error) {
+	if tx := w.currentTx(); tx != nil {
+		name := fmt.Sprintf("sqlc_multi_db_begin_%d", sqliteTxSavepoints.Add(1))
+
+		if _, err := tx.ExecContext(ctx, "SAVEPOINT "+name); err != nil {
+			return nil, w.classifyError(err)
+		}
+
+		return &sqliteTxWrapper{sqliteWrapper: w, ctx: ctx, tx: tx, savepoint: name}, nil
+	}
+
+	tx, err := w.beginTx(ctx, w.txOptions(opts))
+	if err != nil {
+		return nil, w.classifyError(err)
+	}
+
+	return &sqliteTxWrapper{sqliteWrapper: &sqliteWrapper{adapter: w.adapter.WithTx(tx)}, ctx: ctx, tx: tx}, nil
+}
+
+func (t *sqliteTxWrapper) Commit() error {
+	if t.savepoint == "" {
+		return t.classifyError(t.tx.Commit())
+	}
+
+	if t.done {
+		return sql.ErrTxDone
+	}
+
+	t.done = true
+
+	_, err := t.tx.ExecContext(t.ctx, "RELEASE SAVEPOINT "+t.savepoint)
+
+	return t.classifyError(err)
+}
+
+func (t *sqliteTxWrapper) Rollback() error {
+	if t.savepoint == "" {
+		return t.tx.Rollback()
+	}
+
+	if t.done {
+		return sql.ErrTxDone
+	}
+
+	t.done = true
+
+	if _, err := t.tx.ExecContext(t.ctx, "ROLLBACK TO SAVEPOINT "+t.savepoint); err != nil {
+		return err
+	}
+
+	_, err := t.tx.ExecContext(t.ctx, "RELEASE SAVEPOINT "+t.savepoint)
+
+	return err
+}
+
+// beginTx begins a transaction on the executor set with WithDBTX when it can begin one,
+// like a *sql.Conn, and on DB() otherwise.
+func (w *sqliteWrapper) beginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+	if conn, ok := w.adapter.DBTX().(interface {
+		BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+	}); ok {
+		return conn.BeginTx(ctx, opts)
+	}
+
+	return w.adapter.DB().BeginTx(ctx, opts)
+}
+
+// currentTx returns the transaction the wrapper is scoped to with WithTx, if any.
+func (w *sqliteWrapper) currentTx() *sql.Tx {
+	tx, _ := w.adapter.DBTX().(*sql.Tx)
+
+	return tx
+}
+
+// txOptions maps opts to the isolation levels SQLite supports.
+func (w *sqliteWrapper) txOptions(opts *sql.TxOptions) *sql.TxOptions {
+	if opts == nil {
+		return nil
+	}
+
+	// SQLite transactions are always serializable.
+	return &sql.TxOptions{ReadOnly: opts.ReadOnly}
+}
+
+// classifyError classifies an error returned while beginning or committing a transaction.
+func (w *sqliteWrapper) classifyError(err error) error {
+	return classifySQLiteError(err)
+}
@@ -0,0 +1,286 @@
+package generator
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+// multiRowInsert is a single-row INSERT split around its VALUES tuple, so that the tuple
+// can be repeated to insert many rows with one statement.
+type multiRowInsert struct {
+	Prefix       string // everything up to and including VALUES, e.g. "INSERT INTO t (a, b) VALUES "
+	Row          string // the VALUES tuple, e.g. "(?, ?)"
+	Suffix       string // anything after the tuple, e.g. " ON DUPLICATE KEY UPDATE a = VALUES(a)"
+	Placeholders int    // number of placeholders in Row
+}
+
+// querySQL returns the SQL that sqlc emits in a method's doc comment as an indented block.
+func querySQL(docs []string) string {
+	var lines []string
+
+	for _, doc := range docs {
+		if line, ok := strings.CutPrefix(doc, "//  "); ok {
+			lines = append(lines, strings.TrimSpace(line))
+		}
+	}
+
+	return strings.Join(lines, " ")
+}
+
+// parseMultiRowInsert splits a single-row INSERT ... VALUES (...) statement. It reports
+// false for anything a repeated tuple cannot express: other statements, numbered or
+// PostgreSQL placeholders, placeholders outside the tuple, RETURNING clauses and INSERTs
+// that already list several rows.
+func parseMultiRowInsert(query string) (multiRowInsert, bool) {
+	query = strings.TrimSuffix(strings.TrimSpace(query), ";")
+	upper := strings.ToUpper(query)
+
+	if !strings.HasPrefix(upper, "INSERT ") {
+		return multiRowInsert{}, false
+	}
+
+	idx := strings.Index(upper, " VALUES")
+	if idx == -1 {
+		return multiRowInsert{}, false
+	}
+
+	open := idx + len(" VALUES")
+	for open < len(query) && query[open] == ' ' {
+		open++
+	}
+
+	if open == len(query) || query[open] != '(' {
+		return multiRowInsert{}, false
+	}
+
+	end := closingParen(query, open)
+	if end == -1 {
+		return multiRowInsert{}, false
+	}
+
+	insert := multiRowInsert{
+		Prefix: query[:open],
+		Row:    query[open : end+1],
+		Suffix: query[end+1:],
+	}
+
+	trimmedSuffix := strings.TrimSpace(insert.Suffix)
+	if strings.HasPrefix(trimmedSuffix, ",") ||
+		strings.Contains(strings.ToUpper(trimmedSuffix), "RETURNING") ||
+		strings.ContainsAny(trimmedSuffix, "?$") ||
+		strings.Contains(insert.Row, "$") {
+		return multiRowInsert{}, false
+	}
+
+	for i := 0; i < len(insert.Row); i++ {
+		if insert.Row[i] != '?' {
+			continue
+		}
+
+		if i+1 < len(insert.Row) && insert.Row[i+1] >= '0' && insert.Row[i+1] <= '9' {
+			return multiRowInsert{}, false
+		}
+
+		insert.Placeholders++
+	}
+
+	if insert.Placeholders == 0 {
+		return multiRowInsert{}, false
+	}
+
+	return insert, true
+}
+
+// closingParen returns the index of the parenthesis closing the one at open, or -1.
+func closingParen(s string, open int) int {
+	depth := 0
+
+	for i := open; i < len(s); i++ {
+		switch s[i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+
+	return -1
+}
+
+// bulkInsertFor returns the multi-row INSERT to use for a @bulk-for method whose singular
+// method is named singular in the engine package. The zero value (no placeholders) means
+// the bulk method falls back to calling the singular method once per element, which is
+// always the case with @bulk-strategy loop. Otherwise the
+// singular method must return only an error and take a params struct whose fields match
+// the placeholders one for one, in order.
+func bulkInsertFor(strategy, singular string, engData PackageData) multiRowInsert {
+	if strategy == bulkStrategyLoop {
+		return multiRowInsert{}
+	}
+
+	target, ok := findMethod(engData.Methods, singular)
+	if !ok || len(target.Params) != 2 || len(target.Returns) != 1 || target.Returns[0].Type != "error" {
+		return multiRowInsert{}
+	}
+
+	params, ok := engData.Structs[target.Params[1].Type]
+	if !ok {
+		return multiRowInsert{}
+	}
+
+	insert, ok := parseMultiRowInsert(querySQL(target.Docs))
+	if !ok || insert.Placeholders != len(params.Fields) {
+		return multiRowInsert{}
+	}
+
+	return insert
+}
+
+// Bulk strategies selected with the @bulk-strategy annotation on SQLite and MySQL.
+const (
+	bulkStrategyValues = "values" // multi-row INSERT when possible, per-element loop otherwise (the default)
+	bulkStrategyLoop   = "loop"   // always call the singular method once per element
+	bulkStrategyJSON   = "json"   // send the slices as JSON arrays to the engine's own bulk query
+)
+
+func validBulkStrategy(s string) bool {
+	return s == bulkStrategyValues || s == bulkStrategyLoop || s == bulkStrategyJSON
+}
+
+// jsonVar names the local variable holding the JSON encoding of a bulk slice field.
+func jsonVar(field string) string {
+	return strings.ToLower(field[:1]) + field[1:] + "JSON"
+}
+
+// isBulkSlice reports whether a bulk Params field holds one value per element.
+func isBulkSlice(t string) bool { return isSlice(t) && t != typeBytes }
+
+// jsonBulkCall builds the call arguments of an engine bulk query for @bulk-strategy json.
+// The slice fields of the source bulk struct are read from their JSON variables (see
+// jsonVar) as []byte or string, depending on the engine's parameter type, and every other
+// value is converted as usual. The engine method may take a Params struct or, when its
+// query has a single parameter, a scalar. Engine parameters receiving a JSON array must be
+// strings, []byte or interface{}.
+func jsonBulkCall(
+	m MethodInfo,
+	engPkg string,
+	targetMethod MethodInfo,
+	targetStructs map[string]StructInfo,
+	sourceStructs map[string]StructInfo,
+) (string, []Diagnostic, error) {
+	ctxName := m.Params[0].Name
+	argName := m.Params[1].Name
+	bulk := sourceStructs[m.Params[1].Type]
+
+	target := StructInfo{Name: targetMethod.Name}
+	targetStruct, isStruct := StructInfo{}, false
+
+	if len(targetMethod.Params) == 2 {
+		targetStruct, isStruct = targetStructs[targetMethod.Params[1].Type]
+	}
+
+	if isStruct {
+		target = targetStruct
+	} else {
+		for _, p := range targetMethod.Params[min(1, len(targetMethod.Params)):] {
+			target.Fields = append(target.Fields, FieldInfo{Name: p.Name, Type: p.Type})
+		}
+	}
+
+	available := make(map[string]FieldInfo, len(bulk.Fields))
+	for _, f := range bulk.Fields {
+		available[f.Name] = f
+	}
+
+	var (
+		fields []string
+		diags  []Diagnostic
+	)
+
+	for idx, tf := range target.Fields {
+		sf, strategy := findSourceField(tf, idx, target, bulk, available)
+		if d, ok := fieldDiagnostic(target, tf, sf, strategy); ok {
+			diags = append(diags, d)
+		}
+
+		if strategy == matchNone {
+			if !isStruct {
+				fields = append(fields, tf.Name+": "+zeroValue(tf.Type))
+			}
+
+			continue
+		}
+
+		delete(available, sf.Name)
+
+		switch {
+		case !isBulkSlice(sf.Type):
+			fields = append(fields, generateFieldConversion(tf.Name, tf.Type, sf.Type, argName+"."+sf.Name))
+		case tf.Type == typeBytes:
+			fields = append(fields, tf.Name+": "+jsonVar(sf.Name))
+		case tf.Type != typeString && tf.Type != typeAny && tf.Type != "any" && tf.Type != sqlNullString:
+			return "", nil, errInvalidJSONParam(m.Name, tf.Name, tf.Type)
+		default:
+			fields = append(fields, generateFieldConversion(tf.Name, tf.Type, typeString, "string("+jsonVar(sf.Name)+")"))
+		}
+	}
+
+	if !isStruct {
+		args := []string{ctxName}
+		for _, f := range fields {
+			_, expr, _ := strings.Cut(f, ": ")
+			args = append(args, expr)
+		}
+
+		return strings.Join(args, ", "), diags, nil
+	}
+
+	if len(fields) == 0 {
+		return fmt.Sprintf("%s, %s.%s{}", ctxName, engPkg, targetStruct.Name), diags, nil
+	}
+
+	return fmt.Sprintf("%s, %s.%s{\n%s,\n}", ctxName, engPkg, targetStruct.Name, strings.Join(fields, ",\n")), diags, nil
+}
+
+// inferBulkFor treats plural methods as bulk variants of their singular method, as if
+// annotated with @bulk-for: a method such as CreateUsers(ctx, CreateUsersParams) whose
+// params struct has a slice field, when both CreateUser and CreateUserParams exist. Every
+// inferred method is logged so that no method turns into a loop silently.
+func inferBulkFor(methods []MethodInfo, structs map[string]StructInfo) []MethodInfo {
+	inferred := make([]MethodInfo, len(methods))
+	copy(inferred, methods)
+
+	for i, m := range inferred {
+		if m.BulkFor != "" || len(m.Params) < 2 || !strings.HasSuffix(m.Name, "s") {
+			continue
+		}
+
+		if !hasSliceField(structs[m.Params[1].Type]) {
+			continue
+		}
+
+		singular := toSingular(m.Name)
+		if singular == m.Name {
+			continue
+		}
+
+		if _, ok := structs[singular+"Params"]; !ok {
+			continue
+		}
+
+		if _, ok := findMethod(methods, singular); !ok {
+			continue
+		}
+
+		log.Printf("Inferring %s as the bulk variant of %s (annotate it with @bulk-for %s or disable inference)\n",
+			m.Name, singular, singular)
+
+		inferred[i].BulkFor = singular
+	}
+
+	return inferred
+}
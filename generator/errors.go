@@ -14,8 +14,20 @@ var (
 			" to be generated. The auto-looping for bulk inserts handles this by operating on a struct" +
 			" parameter containing a slice",
 	)
+
+	errParamNotMapped = errors.New("no source value matches the engine parameter")
+
+	errJSONParamType = errors.New("a JSON array needs a string, []byte or interface{} engine parameter")
 )
 
 func errUnsupportedSliceDomainStruct(t string) error {
 	return fmt.Errorf("unsupported parameter type: slice of domain struct %s: %w", t, errSliceDomainStructNotSupported)
 }
+
+func errUnmappedParam(method, param string) error {
+	return fmt.Errorf("%s: parameter %s: %w", method, param, errParamNotMapped)
+}
+
+func errInvalidJSONParam(method, param, t string) error {
+	return fmt.Errorf("%s: @bulk-strategy json: parameter %s is %s: %w", method, param, t, errJSONParamType)
+}
@@ -19,6 +19,18 @@ import (
 // Run is the main entry point for the generator.
 // querierPath is the path to the source querier.go file (e.g., postgresdb/querier.go).
 func Run(querierPath string, engines []Engine) {
+	RunWithOptions(querierPath, engines, Options{})
+}
+
+// generatedFile is a rendered file waiting to be written, named relative to the target directory.
+type generatedFile struct {
+	name    string
+	content []byte
+}
+
+// RunWithOptions is like Run but allows configuring the generator with opts.
+// Nothing is written to disk unless every file renders and passes the checks enabled by opts.
+func RunWithOptions(querierPath string, engines []Engine, opts Options) {
 	absQuerierPath, err := filepath.Abs(querierPath)
 	if err != nil {
 		log.Fatalf("resolving querier path: %v", err)
@@ -30,7 +42,27 @@ func Run(querierPath string, engines []Engine) {
 	// 1. Parse source package
 	sourceData := parsePackage(sourceDir)
 
-	// 2. Identify used structs from source methods
+	// 2. Parse all target packages
+	engineData := make(map[string]PackageData)
+
+	for _, engine := range engines {
+		engineDir := filepath.Join(targetDir, engine.Package)
+		data := parsePackage(engineDir)
+
+		if declaresIdent(engineDir, "Adapter", adapterFileName) {
+			data.AdapterMembers = adapterMembers(engineDir, adapterFileName)
+		}
+
+		engineData[engine.Name] = data
+	}
+
+	// 3. Select the methods of the unified Querier
+	sourceData, problems := selectMethods(sourceData, engines, engineData, opts.Methods)
+	if len(problems) > 0 {
+		log.Fatal(formatValidationReport(problems))
+	}
+
+	// 4. Identify used structs from source methods
 	usedStructNames := make(map[string]bool)
 
 	for _, m := range sourceData.Methods {
@@ -58,7 +90,7 @@ func Run(querierPath string, engines []Engine) {
 		return sortedStructs[i].Name < sortedStructs[j].Name
 	})
 
-	// 3. Synthesize missing GetByID methods
+	// 5. Synthesize missing GetByID methods
 	for name := range sourceData.Structs {
 		if !isDomainStruct(name) || strings.HasSuffix(name, "Params") || strings.HasSuffix(name, "Row") {
 			continue
@@ -114,29 +146,126 @@ func Run(querierPath string, engines []Engine) {
 		return sourceData.Methods[i].Name < sourceData.Methods[j].Name
 	})
 
-	// 4. Detect package name and import base
+	if opts.inferBulk() {
+		sourceData.Methods = inferBulkFor(sourceData.Methods, sourceData.Structs)
+	}
+
+	for i, m := range sourceData.Methods {
+		if m.RequireRows {
+			sourceData.Methods[i] = requireRows(m)
+		}
+
+		// A call such as nextval() looks like a read but may write, so it stays on the primary.
+		if _, call := readOnlyQuery(querySQL(m.Docs)); call != "" && m.HasValue && !m.Primary && !m.Replica {
+			log.Printf("Keeping %s on the primary: %s() may write (annotate it with @replica if it only reads)\n",
+				m.Name, call)
+		}
+	}
+
+	// 6. Detect package name and import base
 	packageName := detectPackageName(targetDir)
 	importBase := findImportBase(targetDir)
 
-	// 5. Generate models.go, querier.go, and errors.go
-	generateModels(targetDir, packageName, sortedStructs)
-	generateQuerier(targetDir, packageName, sourceData.Methods)
-	generateErrors(targetDir, packageName)
+	// 7. Render models.go, querier.go, and errors.go
+	files := []generatedFile{
+		{generatedFilePrefix + "models.go", generateModels(packageName, sortedStructs)},
+		{generatedFilePrefix + "querier.go", generateQuerier(packageName, sourceData.Methods, engines)},
+		{generatedFilePrefix + "errors.go", generateErrors(packageName, opts.QueryErrors)},
+	}
+
+	// 8. Validate engines against the source
+	if problems := validateEngines(sourceData.Methods, sourceData.Structs, engines, engineData); len(problems) > 0 {
+		log.Fatal(formatValidationReport(problems))
+	}
 
-	// 6. Parse all target packages
-	engineData := make(map[string]PackageData)
+	// 9. Render wrappers, and adapters for engine packages without a hand-written one
+	var diags []Diagnostic
 
 	for _, engine := range engines {
-		engineDir := filepath.Join(targetDir, engine.Package)
-		engineData[engine.Name] = parsePackage(engineDir)
+		if engineData[engine.Name].AdapterMembers == nil {
+			files = append(files, generatedFile{filepath.Join(engine.Package, adapterFileName), generateAdapter(engine)})
+		}
+
+		files = append(files, generatedFile{
+			fmt.Sprintf("%swrapper_%s.go", generatedFilePrefix, engine.Name),
+			generateWrapper(
+				packageName, importBase, engine, opts.QueryErrors,
+				wrapperMethods(sourceData.Methods, engine), sourceData.Structs, engineData[engine.Name], &diags,
+			),
+		})
+
+		if engine.IsMySQL() {
+			files = append(files,
+				generatedFile{mysqlErrorsFileName, generateMySQLErrors(packageName, true)},
+				generatedFile{mysqlNoDriverErrorsFileName, generateMySQLErrors(packageName, false)},
+			)
+		}
 	}
 
-	// 7. Generate wrappers
-	for _, engine := range engines {
-		generateWrapper(
-			targetDir, packageName, importBase, engine,
-			sourceData.Methods, sourceData.Structs, engineData[engine.Name],
-		)
+	// Render Open unless the package already provides its own
+	if declaresIdent(targetDir, "Open", openFileName) {
+		log.Printf("Skipping %s: Open is already declared in %s\n", openFileName, packageName)
+	} else {
+		declareErr := !declaresIdent(targetDir, "ErrUnsupportedDriver", openFileName)
+		files = append(files, generatedFile{openFileName, generateOpen(packageName, engines, declareErr)})
+	}
+
+	// Render RunInTx unless the package already provides its own
+	if declaresIdent(targetDir, "RunInTx", txFileName) {
+		log.Printf("Skipping %s: RunInTx is already declared in %s\n", txFileName, packageName)
+	} else {
+		files = append(files, generatedFile{txFileName, generateTx(packageName)})
+	}
+
+	// Render RoutingQuerier unless the package already provides its own
+	if declaresIdent(targetDir, "RoutingQuerier", routingFileName) {
+		log.Printf("Skipping %s: RoutingQuerier is already declared in %s\n", routingFileName, packageName)
+	} else {
+		files = append(files, generatedFile{routingFileName, generateRouting(packageName, sourceData.Methods)})
+	}
+
+	// 10. Report field mapping diagnostics
+	reportDiagnostics(diags, opts)
+
+	// 11. Format every file before writing any, so a formatting failure leaves the tree untouched
+	for i, f := range files {
+		files[i].content = formatFile(f.name, f.content)
+	}
+
+	// 12. Write files
+	for _, f := range files {
+		writeFile(targetDir, f.name, f.content)
+	}
+}
+
+// requireRows turns a @require-rows method, which sqlc generates from a :execrows or
+// :execresult query, into one that only returns an error: the wrapper checks the affected
+// rows and returns ErrNotFound when there are none.
+func requireRows(m MethodInfo) MethodInfo {
+	m.Returns = []Return{{Type: "error"}}
+	m.ReturnElem = ""
+	m.ReturnsError = true
+	m.HasValue = false
+	m.IsCreate = false
+	m.IsUpdate = false
+	m.IsReadOnly = false
+
+	return m
+}
+
+// reportDiagnostics prints field mapping diagnostics in warning or strict mode and
+// aborts generation in strict mode.
+func reportDiagnostics(diags []Diagnostic, opts Options) {
+	if len(diags) == 0 || (!opts.Strict && !opts.Warn) {
+		return
+	}
+
+	for _, d := range diags {
+		log.Printf("field mapping: %s", d)
+	}
+
+	if opts.Strict {
+		log.Fatalf("strict mode: %d field(s) dropped or matched by position, no files were written", len(diags))
 	}
 }
 
@@ -166,6 +295,34 @@ func parseQuerierInterface(typeSpec *ast.TypeSpec) ([]MethodInfo, bool) {
 						m.BulkFor = bulkFor
 					}
 				}
+
+				if strategy := extractAnnotation(comment.Text, "@bulk-strategy"); strategy != "" {
+					if !validBulkStrategy(strategy) {
+						log.Fatalf("%s: invalid @bulk-strategy %q: expected values, loop or json", m.Name, strategy)
+					}
+
+					m.BulkStrategy = strategy
+				}
+
+				if chunk := extractAnnotation(comment.Text, "@bulk-chunk"); chunk != "" {
+					n, err := strconv.Atoi(chunk)
+					if err != nil || n <= 0 {
+						log.Fatalf("%s: invalid @bulk-chunk %q: expected a positive number of elements", m.Name, chunk)
+					}
+
+					m.BulkChunk = n
+				}
+
+				if hasAnnotation(comment.Text, "@require-rows") {
+					m.RequireRows = true
+				}
+
+				m.Primary = m.Primary || hasAnnotation(comment.Text, "@primary")
+				m.Replica = m.Replica || hasAnnotation(comment.Text, "@replica")
+
+				m.Engines = append(m.Engines, extractEngineList(comment.Text, "@engines")...)
+				m.SkipEngines = append(m.SkipEngines, extractEngineList(comment.Text, "@skip-engine")...)
+				m.EngineOnly = append(m.EngineOnly, extractEngineList(comment.Text, "@engine-only")...)
 			}
 		}
 
@@ -197,6 +354,12 @@ func parseQuerierInterface(typeSpec *ast.TypeSpec) ([]MethodInfo, bool) {
 
 		m.IsCreate = strings.HasPrefix(m.Name, "Create") && isDomainStruct(m.ReturnElem)
 		m.IsUpdate = strings.HasPrefix(m.Name, "Update") && isDomainStruct(m.ReturnElem)
+		m.IsReadOnly = m.HasValue && isReadOnlyQuery(querySQL(m.Docs))
+
+		if m.Primary && m.Replica {
+			log.Fatalf("%s: @primary and @replica cannot be combined", m.Name)
+		}
+
 		methods = append(methods, m)
 	}
 
@@ -276,7 +439,7 @@ func parsePackage(dir string) PackageData {
 	return PackageData{Methods: methods, Structs: structs}
 }
 
-func generateModels(dir, packageName string, structs []StructInfo) {
+func generateModels(packageName string, structs []StructInfo) []byte {
 	t := template.Must(template.New("models").Parse(modelsTemplate))
 
 	var buf bytes.Buffer
@@ -289,10 +452,46 @@ func generateModels(dir, packageName string, structs []StructInfo) {
 		log.Fatalf("executing models template: %v", err)
 	}
 
-	writeFile(dir, generatedFilePrefix+"models.go", buf.Bytes())
+	return buf.Bytes()
 }
 
-func generateQuerier(dir, packageName string, methods []MethodInfo) {
+// wrapperMethods returns the methods implemented by the engine's wrapper: every method of
+// the unified Querier, plus the @engine-only methods of this engine.
+func wrapperMethods(methods []MethodInfo, engine Engine) []MethodInfo {
+	filtered := make([]MethodInfo, 0, len(methods))
+
+	for _, m := range methods {
+		if len(m.EngineOnly) == 0 || m.SupportsEngine(engine.Name) {
+			filtered = append(filtered, m)
+		}
+	}
+
+	return filtered
+}
+
+// extensions returns the engine-specific interfaces to generate, one per engine that has
+// @engine-only methods, in the order the engines were configured.
+func extensions(methods []MethodInfo, engines []Engine) []Extension {
+	var exts []Extension
+
+	for _, engine := range engines {
+		ext := Extension{Engine: engine}
+
+		for _, m := range methods {
+			if len(m.EngineOnly) > 0 && m.SupportsEngine(engine.Name) {
+				ext.Methods = append(ext.Methods, m)
+			}
+		}
+
+		if len(ext.Methods) > 0 {
+			exts = append(exts, ext)
+		}
+	}
+
+	return exts
+}
+
+func generateQuerier(packageName string, methods []MethodInfo, engines []Engine) []byte {
 	t := template.Must(template.New("querier").Funcs(template.FuncMap{
 		"joinParamsSignature": joinParamsSignature,
 		"joinReturns":         joinReturns,
@@ -303,39 +502,169 @@ func generateQuerier(dir, packageName string, methods []MethodInfo) {
 	data := map[string]interface{}{
 		"PackageName": packageName,
 		"Methods":     methods,
+		"Extensions":  extensions(methods, engines),
 	}
 	if err := t.Execute(&buf, data); err != nil {
 		log.Fatalf("executing querier template: %v", err)
 	}
 
-	writeFile(dir, generatedFilePrefix+"querier.go", buf.Bytes())
+	return buf.Bytes()
 }
 
-func generateErrors(dir, packageName string) {
+func generateErrors(packageName string, queryErrors bool) []byte {
 	t := template.Must(template.New("errors").Parse(errorsTemplate))
 
 	var buf bytes.Buffer
 
 	data := map[string]interface{}{
 		"PackageName": packageName,
+		"QueryErrors": queryErrors,
 	}
 	if err := t.Execute(&buf, data); err != nil {
 		log.Fatalf("executing errors template: %v", err)
 	}
 
-	writeFile(dir, generatedFilePrefix+"errors.go", buf.Bytes())
+	return buf.Bytes()
+}
+
+// mysqlErrorsFileName and mysqlNoDriverErrorsFileName hold mysqlErrorNumber with and
+// without go-sql-driver/mysql, selected by the nomysqldriver build tag.
+const (
+	mysqlErrorsFileName         = generatedFilePrefix + "errors_mysql.go"
+	mysqlNoDriverErrorsFileName = generatedFilePrefix + "errors_mysql_nodriver.go"
+)
+
+// generateMySQLErrors renders mysqlErrorNumber, reading go-sql-driver/mysql errors when
+// driver is set.
+func generateMySQLErrors(packageName string, driver bool) []byte {
+	t := template.Must(template.New("mysqlErrors").Parse(mysqlErrorsTemplate))
+
+	var buf bytes.Buffer
+
+	data := map[string]interface{}{
+		"PackageName": packageName,
+		"Driver":      driver,
+	}
+	if err := t.Execute(&buf, data); err != nil {
+		log.Fatalf("executing mysql errors template: %v", err)
+	}
+
+	return buf.Bytes()
+}
+
+// txFileName is the transaction runner generated in the target package.
+const txFileName = generatedFilePrefix + "tx.go"
+
+// generateTx renders RunInTx.
+func generateTx(packageName string) []byte {
+	t := template.Must(template.New("tx").Parse(txTemplate))
+
+	var buf bytes.Buffer
+
+	if err := t.Execute(&buf, map[string]interface{}{"PackageName": packageName}); err != nil {
+		log.Fatalf("executing tx template: %v", err)
+	}
+
+	return buf.Bytes()
+}
+
+// routingFileName is the read/write routing Querier generated in the target package.
+const routingFileName = generatedFilePrefix + "routing.go"
+
+// generateRouting renders RoutingQuerier.
+func generateRouting(packageName string, methods []MethodInfo) []byte {
+	t := template.Must(template.New("routing").Funcs(template.FuncMap{
+		"joinParamsSignature": joinParamsSignature,
+		"joinReturns":         joinReturns,
+	}).Parse(routingTemplate))
+
+	var buf bytes.Buffer
+
+	data := map[string]interface{}{
+		"PackageName": packageName,
+		"Methods":     methods,
+	}
+	if err := t.Execute(&buf, data); err != nil {
+		log.Fatalf("executing routing template: %v", err)
+	}
+
+	return buf.Bytes()
+}
+
+// adapterFileName is the adapter generated in engine packages.
+const adapterFileName = generatedFilePrefix + "adapter.go"
+
+// generateAdapter renders the Adapter the wrapper expects in the engine package.
+func generateAdapter(engine Engine) []byte {
+	t := template.Must(template.New("adapter").Parse(adapterTemplate))
+
+	var buf bytes.Buffer
+
+	if err := t.Execute(&buf, map[string]interface{}{"Package": engine.Package, "Engine": engine}); err != nil {
+		log.Fatalf("executing adapter template: %v", err)
+	}
+
+	return buf.Bytes()
 }
 
 func generateWrapper(
-	dir, packageName, importBase string,
+	packageName, importBase string,
+	engine Engine,
+	queryErrors bool,
+	methods []MethodInfo,
+	structs map[string]StructInfo,
+	engData PackageData,
+	diags *[]Diagnostic,
+) []byte {
+	t := template.Must(template.New("wrapper").
+		Funcs(wrapperFuncMap(engine, methods, structs, engData, diags)).
+		Parse(wrapperTemplate))
+
+	var buf bytes.Buffer
+
+	data := map[string]interface{}{
+		"Engine":      engine,
+		"Methods":     methods,
+		"Structs":     structs,
+		"ImportBase":  importBase,
+		"PackageName": packageName,
+		"Extensions":  extensions(methods, []Engine{engine}),
+		"QueryErrors": queryErrors,
+	}
+
+	if err := t.Execute(&buf, data); err != nil {
+		log.Fatalf("executing wrapper template: %v", err)
+	}
+
+	return buf.Bytes()
+}
+
+// wrapperFuncMap returns the template functions used by wrapperTemplate. methods and
+// structs describe the source package, engData the engine package being wrapped. Field
+// mapping diagnostics are appended to diags, which may be nil to discard them.
+func wrapperFuncMap(
 	engine Engine,
 	methods []MethodInfo,
 	structs map[string]StructInfo,
 	engData PackageData,
-) {
-	t := template.Must(template.New("wrapper").Funcs(template.FuncMap{
+	diags *[]Diagnostic,
+) template.FuncMap {
+	report := func(method string, found []Diagnostic) {
+		if diags == nil {
+			return
+		}
+
+		for _, d := range found {
+			d.Method = method
+			d.Engine = engine.Name
+			*diags = append(*diags, d)
+		}
+	}
+
+	return template.FuncMap{
 		"joinParamsSignature": joinParamsSignature,
 		"joinReturns":         joinReturns,
+		"joinNamedReturns":    joinNamedReturns,
 		"isSlice":             isSlice,
 		"firstReturnType":     firstReturnType,
 		"isDomainStruct":      isDomainStructFunc,
@@ -345,14 +674,28 @@ func generateWrapper(
 		"getSliceField":       getSliceField,
 		"toSingular":          toSingular,
 		"trimPrefix":          strings.TrimPrefix,
+		"getMethod": func(name string) MethodInfo {
+			m, _ := findMethod(methods, name)
+
+			return m
+		},
 		"getTargetMethod": func(name string) MethodInfo {
-			for _, m := range engData.Methods {
-				if m.Name == name {
-					return m
-				}
-			}
+			m, _ := findMethod(engData.Methods, name)
+
+			return m
+		},
+		"bulkInsert": func(strategy, singular string) multiRowInsert {
+			return bulkInsertFor(strategy, singular, engData)
+		},
+		"jsonVar":     jsonVar,
+		"isBulkSlice": isBulkSlice,
+		"jsonBulkCall": func(m MethodInfo) (string, error) {
+			targetMethod, _ := findMethod(engData.Methods, m.Name)
+
+			call, found, err := jsonBulkCall(m, engine.Package, targetMethod, engData.Structs, structs)
+			report(m.Name, found)
 
-			return MethodInfo{}
+			return call, err
 		},
 		"getTargetStruct": func(name string) StructInfo {
 			if engData.Structs == nil {
@@ -362,19 +705,12 @@ func generateWrapper(
 			return engData.Structs[name]
 		},
 		"joinParamsCall": func(params []Param, engPkg string, targetMethodName string) (string, error) {
-			targetMethod := MethodInfo{}
+			targetMethod, _ := findMethod(engData.Methods, targetMethodName)
 
-			if engData.Methods != nil {
-				for _, m := range engData.Methods {
-					if m.Name == targetMethodName {
-						targetMethod = m
+			call, found, err := joinParamsCall(params, engPkg, targetMethod, engData.Structs, structs)
+			report(targetMethodName, found)
 
-						break
-					}
-				}
-			}
-
-			return joinParamsCall(params, engPkg, targetMethod, engData.Structs, structs)
+			return call, err
 		},
 		"dict": func(values ...interface{}) (map[string]interface{}, error) {
 			if len(values)%2 != 0 {
@@ -393,12 +729,26 @@ func generateWrapper(
 
 			return dict, nil
 		},
-		"hasSuffix":               strings.HasSuffix,
 		"toSnakeCase":             toSnakeCase,
 		"quote":                   quote,
 		"generateFieldConversion": generateFieldConversion,
-		"hasParam":                hasParam,
-		"paramHasField":           paramHasField,
+		// Only used for results, which are never paired by position.
+		"convertStructFields": func(method string, targetStruct, sourceStruct StructInfo, sourcePrefix string) []string {
+			fields, found := convertStructFields(targetStruct, sourceStruct, sourcePrefix, false)
+			report(method, found)
+
+			return fields
+		},
+		"bulkLoopFields": func(
+			method string, targetStruct, bulkStruct StructInfo, sliceField FieldInfo, argName string,
+		) []string {
+			fields, found := bulkLoopFields(targetStruct, bulkStruct, sliceField, argName)
+			report(method, found)
+
+			return fields
+		},
+		"hasParam":      hasParam,
+		"paramHasField": paramHasField,
 		"getTableName": func(structName string) string {
 			extractTableName := func(docs []string) (string, bool) {
 				clauses := []struct {
@@ -452,23 +802,7 @@ func generateWrapper(
 
 			return strings.ToLower(inflection.Plural(structName))
 		},
-	}).Parse(wrapperTemplate))
-
-	var buf bytes.Buffer
-
-	data := map[string]interface{}{
-		"Engine":      engine,
-		"Methods":     methods,
-		"Structs":     structs,
-		"ImportBase":  importBase,
-		"PackageName": packageName,
 	}
-
-	if err := t.Execute(&buf, data); err != nil {
-		log.Fatalf("executing wrapper template: %v", err)
-	}
-
-	writeFile(dir, fmt.Sprintf("%swrapper_%s.go", generatedFilePrefix, engine.Name), buf.Bytes())
 }
 
 func exprToString(expr ast.Expr) string {
@@ -0,0 +1,2207 @@
+package generator
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"text/template"
+)
+
+var (
+	errTestInvalidDictCall       = errors.New("invalid dict call")
+	errTestDictKeysMustBeStrings = errors.New("dict keys must be strings")
+)
+
+// validationReport returns the report printed by the generator for the problems found by
+// validateEngines, or an empty string when the engines are consistent.
+func validationReport(
+	methods []MethodInfo, structs map[string]StructInfo, engines []Engine, engineData map[string]PackageData,
+) string {
+	problems := validateEngines(methods, structs, engines, engineData)
+	if len(problems) == 0 {
+		return ""
+	}
+
+	return formatValidationReport(problems)
+}
+
+// selectMethodsReport is selectMethods, returning the report of conflicting signatures
+// ("" when there are none).
+func selectMethodsReport(
+	source PackageData, engines []Engine, engineData map[string]PackageData, set MethodSet,
+) (PackageData, string) {
+	data, problems := selectMethods(source, engines, engineData, set)
+	if len(problems) == 0 {
+		return data, ""
+	}
+
+	return data, formatValidationReport(problems)
+}
+
+func TestJoinParamsCall(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		params  []Param
+		engPkg  string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "Simple Params",
+			params: []Param{
+				{Name: "ctx", Type: "context.Context"},
+				{Name: "id", Type: "int64"},
+			},
+			engPkg: "sqlitedb",
+			want:   "ctx, id",
+		},
+		{
+			name: "Domain Struct Param",
+			params: []Param{
+				{Name: "user", Type: "User"},
+			},
+			engPkg: "postgresdb",
+			want:   "postgresdb.User(user)",
+		},
+		{
+			name: "Unsupported Slice of Domain Struct",
+			params: []Param{
+				{Name: "users", Type: "[]User"},
+			},
+			engPkg:  "postgresdb",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, _, err := joinParamsCall(tt.params, tt.engPkg, MethodInfo{}, nil, nil)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("joinParamsCall() error = %v, wantErr %v", err, tt.wantErr)
+
+				return
+			}
+
+			if got != tt.want {
+				t.Errorf("joinParamsCall() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWrapperTemplate(t *testing.T) {
+	t.Parallel()
+
+	// Mock engines
+	sqlite := Engine{Name: "sqlite", Package: "sqlitedb"}
+
+	// Mock structs
+	structs := map[string]StructInfo{
+		"CreateUserParams": {
+			Name: "CreateUserParams",
+			Fields: []FieldInfo{
+				{Name: "Username", Type: "string"},
+			},
+		},
+		"CreateUsersParams": {
+			Name: "CreateUsersParams",
+			Fields: []FieldInfo{
+				{Name: "Usernames", Type: "[]string"},
+			},
+		},
+	}
+
+	// Mock methods
+	methods := []MethodInfo{
+		{
+			Name: "CreateUsers",
+			Params: []Param{
+				{Name: "ctx", Type: "context.Context"},
+				{Name: "arg", Type: "CreateUsersParams"},
+			},
+			Returns: []Return{{Type: "error"}},
+			Docs:    []string{"// CreateUsers creates users"},
+			BulkFor: "CreateUser", // as inferred by InferBulkFor
+		},
+	}
+
+	wrapperFuncs := wrapperFuncMap(sqlite, methods, structs, PackageData{}, nil)
+
+	funcMap := template.FuncMap{
+		"hasParam":            hasParam,
+		"paramHasField":       paramHasField,
+		"joinParamsSignature": joinParamsSignature,
+		"joinReturns":         joinReturns,
+		"joinNamedReturns":    wrapperFuncs["joinNamedReturns"],
+		"isSlice":             isSlice,
+		"firstReturnType":     firstReturnType,
+		"isDomainStruct":      isDomainStructFunc,
+		"zeroValue":           zeroValue,
+		"getStruct":           func(name string) StructInfo { return structs[name] },
+		"hasSliceField":       hasSliceField,
+		"getSliceField":       getSliceField,
+		"toSingular":          toSingular,
+		"trimPrefix":          strings.TrimPrefix,
+		"dict": func(values ...interface{}) (map[string]interface{}, error) {
+			if len(values)%2 != 0 {
+				return nil, errTestInvalidDictCall
+			}
+
+			dict := make(map[string]interface{}, len(values)/2)
+			for i := 0; i < len(values); i += 2 {
+				key, ok := values[i].(string)
+				if !ok {
+					return nil, errTestDictKeysMustBeStrings
+				}
+
+				dict[key] = values[i+1]
+			}
+
+			return dict, nil
+		},
+		"getTargetMethod": func(name string) MethodInfo {
+			if name == "CreateUsers" {
+				return MethodInfo{
+					Name: "CreateUsers",
+					Params: []Param{
+						{Name: "ctx", Type: "context.Context"},
+						{Name: "arg", Type: "CreateUsersParams"},
+					},
+					Returns: []Return{{Type: "error"}},
+				}
+			}
+
+			return MethodInfo{}
+		},
+		"getTargetStruct": func(name string) StructInfo { return structs[name] },
+		"joinParamsCall": func(params []Param, engPkg string, targetMethodName string) (string, error) {
+			targetMethod := MethodInfo{}
+			if targetMethodName == "CreateUsers" {
+				targetMethod = MethodInfo{
+					Name: "CreateUsers",
+					Params: []Param{
+						{Name: "ctx", Type: "context.Context"},
+						{Name: "arg", Type: "CreateUsersParams"},
+					},
+					Returns: []Return{{Type: "error"}},
+				}
+			}
+
+			call, _, err := joinParamsCall(params, engPkg, targetMethod, structs, structs)
+
+			return call, err
+		},
+		"toSnakeCase":             toSnakeCase,
+		"quote":                   quote,
+		"generateFieldConversion": generateFieldConversion,
+		"convertStructFields":     wrapperFuncs["convertStructFields"],
+		"bulkLoopFields":          wrapperFuncs["bulkLoopFields"],
+		"bulkInsert":              wrapperFuncs["bulkInsert"],
+		"getMethod":               wrapperFuncs["getMethod"],
+		"jsonVar":                 wrapperFuncs["jsonVar"],
+		"isBulkSlice":             wrapperFuncs["isBulkSlice"],
+		"jsonBulkCall":            wrapperFuncs["jsonBulkCall"],
+		"zeroReturn": func(m MethodInfo) string {
+			if m.ReturnsSelf {
+				return "nil"
+			}
+
+			return "0"
+		},
+		"getTableName": func(_ string) string { return "users" },
+	}
+
+	tmpl, err := template.New("wrapper").Funcs(funcMap).Parse(wrapperTemplate)
+	if err != nil {
+		t.Fatalf("failed to parse template: %v", err)
+	}
+
+	data := map[string]interface{}{
+		"Engine":      sqlite,
+		"Methods":     methods,
+		"Structs":     structs,
+		"ImportBase":  "github.com/example/project/pkg/database",
+		"PackageName": "database",
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		t.Fatalf("failed to execute template: %v", err)
+	}
+
+	output := buf.String()
+
+	// Verify auto-looping was triggered
+	if !strings.Contains(output, "for i, v := range arg.Usernames") {
+		t.Errorf("expected output to contain loop over arg.Usernames, but it didn't\n%s", output)
+	}
+
+	// Verify the exported constructors
+	if !strings.Contains(output, "func NewSQLiteQuerier(db *sql.DB) Querier {") ||
+		!strings.Contains(output, "func NewSQLiteQuerierFromAdapter(adapter *sqlitedb.Adapter) Querier {") {
+		t.Errorf("expected exported NewSQLiteQuerier constructors\n%s", output)
+	}
+
+	// Verify the loop runs atomically
+	if !strings.Contains(output, "return w.runBulk(ctx, func(a *sqlitedb.Adapter) error {") ||
+		!strings.Contains(output, "err := a.CreateUser(ctx, sqlitedb.CreateUserParams{") {
+		t.Errorf("expected the loop to run inside runBulk on the scoped adapter\n%s", output)
+	}
+
+	// Bulk calls nested in BeginTx or RunInTx savepoints each take their own savepoint.
+	if !strings.Contains(output, `name := fmt.Sprintf("sqlc_multi_db_bulk_%d", sqliteBulkSavepoints.Add(1))`) ||
+		!strings.Contains(output, `tx.ExecContext(ctx, "SAVEPOINT "+name)`) ||
+		!strings.Contains(output, `tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+name)`) {
+		t.Errorf("expected runBulk to use a numbered savepoint inside an existing transaction\n%s", output)
+	}
+
+	// Verify field mapping by type
+	if !strings.Contains(output, "Username: v,") {
+		t.Errorf("expected output to contain 'Username: v,', but it didn't\n%s", output)
+	}
+
+	// 2. Test GetStatus (should NOT loop because GetStatuParams does not exist)
+	methods = []MethodInfo{
+		{
+			Name: "GetStatus",
+			Params: []Param{
+				{Name: "ctx", Type: "context.Context"},
+				{Name: "hash", Type: "string"},
+			},
+			Returns: []Return{{Type: "Status"}, {Type: "error"}},
+			Docs:    []string{"// GetStatus gets status"},
+		},
+	}
+
+	data["Methods"] = methods
+
+	buf.Reset()
+
+	if err := tmpl.Execute(&buf, data); err != nil {
+		t.Fatalf("failed to execute template: %v", err)
+	}
+
+	output = buf.String()
+	if strings.Contains(output, "for _, v := range") {
+		t.Errorf("expected output NOT to contain loop for GetStatus, but it did\n%s", output)
+	}
+
+	// 3. Test ReturnsSelf (WithTx)
+	methods = []MethodInfo{
+		{
+			Name:         "WithTx",
+			Params:       []Param{{Name: "tx", Type: "*sql.Tx"}},
+			Returns:      []Return{{Type: "Querier"}, {Type: "error"}},
+			ReturnsSelf:  true,
+			ReturnsError: true,
+			HasValue:     true,
+			Docs:         []string{"// WithTx returns a new Querier with transaction"},
+		},
+	}
+
+	data["Methods"] = methods
+
+	buf.Reset()
+
+	if err := tmpl.Execute(&buf, data); err != nil {
+		t.Fatalf("failed to execute template: %v", err)
+	}
+
+	output = buf.String()
+	if !strings.Contains(output, "nil, ErrNotFound") {
+		t.Errorf("expected output to contain 'nil, ErrNotFound' for WithTx, but it didn't\n%s", output)
+	}
+
+	if !strings.Contains(output, "nil, err") {
+		t.Errorf("expected output to contain 'nil, err' for WithTx, but it didn't\n%s", output)
+	}
+
+	// 4. Test sql.NullString conversion
+	methods = []MethodInfo{
+		{
+			Name: "CreateUser",
+			Params: []Param{
+				{Name: "ctx", Type: "context.Context"},
+				{Name: "user", Type: "User"},
+			},
+			Returns: []Return{{Type: "error"}},
+		},
+	}
+
+	structs["CreateUserParams"] = StructInfo{
+		Name: "CreateUserParams",
+		Fields: []FieldInfo{
+			{Name: "Bio", Type: "sql.NullString"},
+		},
+	}
+	structs["User"] = StructInfo{
+		Name: "User",
+		Fields: []FieldInfo{
+			{Name: "Bio", Type: "string"},
+		},
+	}
+
+	funcMap["getTargetMethod"] = func(name string) MethodInfo {
+		if name == "CreateUser" {
+			return MethodInfo{
+				Name: "CreateUser",
+				Params: []Param{
+					{Name: "ctx", Type: "context.Context"},
+					{Name: "arg", Type: "CreateUserParams"},
+				},
+				Returns: []Return{{Type: "error"}},
+			}
+		}
+
+		return MethodInfo{}
+	}
+
+	funcMap["joinParamsCall"] = func(params []Param, engPkg string, _ string) (string, error) {
+		targetMethod := MethodInfo{
+			Name: "CreateUser",
+			Params: []Param{
+				{Name: "ctx", Type: "context.Context"},
+				{Name: "arg", Type: "CreateUserParams"},
+			},
+			Returns: []Return{{Type: "error"}},
+		}
+
+		call, _, err := joinParamsCall(params, engPkg, targetMethod, structs, structs)
+
+		return call, err
+	}
+	funcMap["getTableName"] = func(_ string) string { return "users" }
+
+	tmpl, err = template.New("wrapper").Funcs(funcMap).Parse(wrapperTemplate)
+	if err != nil {
+		t.Fatalf("failed to parse template: %v", err)
+	}
+
+	data["Methods"] = methods
+
+	buf.Reset()
+
+	if err := tmpl.Execute(&buf, data); err != nil {
+		t.Fatalf("failed to execute template: %v", err)
+	}
+
+	output = buf.String()
+
+	expectedConversion := "Bio: sql.NullString{String: user.Bio, Valid: true}"
+	if !strings.Contains(output, expectedConversion) {
+		t.Errorf("expected output to contain '%s', but it didn't\n%s", expectedConversion, output)
+	}
+}
+
+// TestJoinParamsCallFieldMapping tests that joinParamsCall correctly maps
+// struct fields even when field names differ between source and target.
+// This is a regression test for the MySQL LIMIT parameter issue where
+// sqlc generates different field names (e.g., BatchSize vs Limit).
+func TestJoinParamsCallFieldMapping(t *testing.T) {
+	t.Parallel()
+
+	// Source structs (domain) - what the wrapper API uses
+	sourceStructs := map[string]StructInfo{
+		"GetStuckNarFilesParams": {
+			Name: "GetStuckNarFilesParams",
+			Fields: []FieldInfo{
+				{Name: "CutoffTime", Type: "time.Time"},
+				{Name: "BatchSize", Type: "int32"},
+			},
+		},
+	}
+
+	// Target structs (adapter) - what the database engine generates
+	// MySQL generates different names: CreatedAt instead of CutoffTime, Limit instead of BatchSize
+	targetStructs := map[string]StructInfo{
+		"GetStuckNarFilesParams": {
+			Name: "GetStuckNarFilesParams",
+			Fields: []FieldInfo{
+				{Name: "CreatedAt", Type: "time.Time"},
+				{Name: "Limit", Type: "int32"},
+			},
+		},
+	}
+
+	// Target method info
+	targetMethod := MethodInfo{
+		Name: "GetStuckNarFiles",
+		Params: []Param{
+			{Name: "ctx", Type: "context.Context"},
+			{Name: "arg", Type: "GetStuckNarFilesParams"},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		params  []Param
+		engPkg  string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "Field mapping with different names",
+			params: []Param{
+				{Name: "ctx", Type: "context.Context"},
+				{Name: "arg", Type: "GetStuckNarFilesParams"},
+			},
+			engPkg: "mysqldb",
+			// Expected: both fields should be mapped even though names differ
+			// The target struct uses its own field names (CreatedAt, Limit), not source names
+			want: "ctx, mysqldb.GetStuckNarFilesParams{\nCreatedAt: arg.CutoffTime,\nLimit: arg.BatchSize,\n}",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, _, err := joinParamsCall(tt.params, tt.engPkg, targetMethod, targetStructs, sourceStructs)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("joinParamsCall() error = %v, wantErr %v", err, tt.wantErr)
+
+				return
+			}
+
+			if got != tt.want {
+				t.Errorf("joinParamsCall() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// renderWrapper renders wrapperTemplate for a single engine using the production template functions.
+func renderWrapper(
+	t *testing.T,
+	engine Engine,
+	methods []MethodInfo,
+	structs map[string]StructInfo,
+	engData PackageData,
+) string {
+	t.Helper()
+
+	tmpl, err := template.New("wrapper").
+		Funcs(wrapperFuncMap(engine, methods, structs, engData, nil)).
+		Parse(wrapperTemplate)
+	if err != nil {
+		t.Fatalf("failed to parse template: %v", err)
+	}
+
+	data := map[string]interface{}{
+		"Engine":      engine,
+		"Methods":     methods,
+		"Structs":     structs,
+		"ImportBase":  "github.com/example/project/pkg/database",
+		"PackageName": "database",
+		"Extensions":  extensions(methods, []Engine{engine}),
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		t.Fatalf("failed to execute template: %v", err)
+	}
+
+	return buf.String()
+}
+
+func TestConvertStructFields(t *testing.T) {
+	t.Parallel()
+
+	target := StructInfo{
+		Name: "Book",
+		Fields: []FieldInfo{
+			{Name: "ID", Type: "int64"},
+			{Name: "Title", Type: "string"},
+			{Name: "Description", Type: "sql.NullString"},
+		},
+	}
+	source := StructInfo{
+		Name: "GetBookRow",
+		Fields: []FieldInfo{
+			{Name: "Id", Type: "int64"},
+			{Name: "Title", Type: "string"},
+			{Name: "Description", Type: "string"},
+		},
+	}
+
+	want := []string{
+		"ID: res.Id",
+		"Title: res.Title",
+		"Description: sql.NullString{String: res.Description, Valid: true}",
+	}
+
+	got, diags := convertStructFields(target, source, "res.", false)
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("ConvertStructFields() = %q, want %q", got, want)
+	}
+
+	if len(diags) != 0 {
+		t.Errorf("ConvertStructFields() diagnostics = %v, want none", diags)
+	}
+}
+
+// TestWrapperTemplateDifferentlyNamedStructs verifies that conversions use the struct the
+// engine method actually returns when sqlc names it differently from the source type.
+func TestWrapperTemplateDifferentlyNamedStructs(t *testing.T) {
+	t.Parallel()
+
+	structs := map[string]StructInfo{
+		"Book": {
+			Name: "Book",
+			Fields: []FieldInfo{
+				{Name: "ID", Type: "int64"},
+				{Name: "Title", Type: "string"},
+			},
+		},
+	}
+
+	methods := []MethodInfo{
+		{
+			Name:         "GetBook",
+			Params:       []Param{{Name: "ctx", Type: "context.Context"}, {Name: "id", Type: "int64"}},
+			Returns:      []Return{{Type: "Book"}, {Type: "error"}},
+			ReturnElem:   "Book",
+			ReturnsError: true,
+			HasValue:     true,
+		},
+		{
+			Name:         "ListBooks",
+			Params:       []Param{{Name: "ctx", Type: "context.Context"}},
+			Returns:      []Return{{Type: "[]Book"}, {Type: "error"}},
+			ReturnElem:   "Book",
+			ReturnsError: true,
+			HasValue:     true,
+		},
+	}
+
+	engData := PackageData{
+		Methods: []MethodInfo{
+			{
+				Name:    "GetBook",
+				Params:  []Param{{Name: "ctx", Type: "context.Context"}, {Name: "id", Type: "int64"}},
+				Returns: []Return{{Type: "GetBookRow"}, {Type: "error"}},
+			},
+			{
+				Name:    "ListBooks",
+				Params:  []Param{{Name: "ctx", Type: "context.Context"}},
+				Returns: []Return{{Type: "[]ListBooksRow"}, {Type: "error"}},
+			},
+		},
+		Structs: map[string]StructInfo{
+			"GetBookRow": {
+				Name:   "GetBookRow",
+				Fields: []FieldInfo{{Name: "ID", Type: "int64"}, {Name: "Title", Type: "string"}},
+			},
+			"ListBooksRow": {
+				Name:   "ListBooksRow",
+				Fields: []FieldInfo{{Name: "ID", Type: "int64"}, {Name: "Title", Type: "string"}},
+			},
+		},
+	}
+
+	output := renderWrapper(t, Engine{Name: "sqlite", Package: "sqlitedb"}, methods, structs, engData)
+
+	for _, want := range []string{"ID: res.ID,", "Title: res.Title,", "ID: v.ID,", "Title: v.Title,"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected output to contain %q, but it didn't\n%s", want, output)
+		}
+	}
+}
+
+// TestJoinParamsCallReshaped tests that joinParamsCall maps parameters when sqlc groups
+// them differently on the target engine than on the source engine.
+func TestJoinParamsCallReshaped(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name          string
+		params        []Param
+		targetMethod  MethodInfo
+		sourceStructs map[string]StructInfo
+		targetStructs map[string]StructInfo
+		want          string
+		wantErr       bool
+	}{
+		{
+			// Postgres reuses $1 while SQLite repeats ?, so sqlc generates a Params struct
+			// with a duplicated field on SQLite only.
+			name: "Scalars to Params struct",
+			params: []Param{
+				{Name: "ctx", Type: "context.Context"},
+				{Name: "id", Type: "int64"},
+			},
+			targetMethod: MethodInfo{
+				Name: "GetBookOrParent",
+				Params: []Param{
+					{Name: "ctx", Type: "context.Context"},
+					{Name: "arg", Type: "GetBookOrParentParams"},
+				},
+			},
+			targetStructs: map[string]StructInfo{
+				"GetBookOrParentParams": {
+					Name: "GetBookOrParentParams",
+					Fields: []FieldInfo{
+						{Name: "ID", Type: "int64"},
+						{Name: "ID_2", Type: "sql.NullInt64"},
+					},
+				},
+			},
+			want: "ctx, sqlitedb.GetBookOrParentParams{\nID: id,\nID_2: sql.NullInt64{Int64: id, Valid: true},\n}",
+		},
+		{
+			name: "Params struct to scalars",
+			params: []Param{
+				{Name: "ctx", Type: "context.Context"},
+				{Name: "arg", Type: "ListBooksPageParams"},
+			},
+			targetMethod: MethodInfo{
+				Name: "ListBooksPage",
+				Params: []Param{
+					{Name: "ctx", Type: "context.Context"},
+					{Name: "author", Type: "string"},
+					{Name: "limit", Type: "int64"},
+				},
+			},
+			sourceStructs: map[string]StructInfo{
+				"ListBooksPageParams": {
+					Name: "ListBooksPageParams",
+					Fields: []FieldInfo{
+						{Name: "Author", Type: "string"},
+						{Name: "Limit", Type: "int32"},
+					},
+				},
+			},
+			want: "ctx, arg.Author, int64(arg.Limit)",
+		},
+		{
+			name: "Unmapped scalar",
+			params: []Param{
+				{Name: "ctx", Type: "context.Context"},
+				{Name: "arg", Type: "ListBooksPageParams"},
+			},
+			targetMethod: MethodInfo{
+				Name: "ListBooksPage",
+				Params: []Param{
+					{Name: "ctx", Type: "context.Context"},
+					{Name: "offset", Type: "int64"},
+				},
+			},
+			sourceStructs: map[string]StructInfo{
+				"ListBooksPageParams": {
+					Name: "ListBooksPageParams",
+					Fields: []FieldInfo{
+						{Name: "Author", Type: "string"},
+						{Name: "Limit", Type: "int32"},
+					},
+				},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, _, err := joinParamsCall(tt.params, "sqlitedb", tt.targetMethod, tt.targetStructs, tt.sourceStructs)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("joinParamsCall() error = %v, wantErr %v", err, tt.wantErr)
+
+				return
+			}
+
+			if got != tt.want {
+				t.Errorf("joinParamsCall() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConvertStructFieldsDiagnostics(t *testing.T) {
+	t.Parallel()
+
+	target := StructInfo{
+		Name: "Book",
+		Fields: []FieldInfo{
+			{Name: "ID", Type: "int64"},
+			{Name: "Title", Type: "string"},
+			{Name: "Subtitle", Type: "string"},
+		},
+	}
+	source := StructInfo{
+		Name: "GetBookRow",
+		Fields: []FieldInfo{
+			{Name: "ID", Type: "int64"},
+			{Name: "Name", Type: "string"},
+		},
+	}
+
+	fields, diags := convertStructFields(target, source, "res.", false)
+
+	wantFields := []string{"ID: res.ID"}
+	if strings.Join(fields, "\n") != strings.Join(wantFields, "\n") {
+		t.Errorf("ConvertStructFields() = %q, want %q", fields, wantFields)
+	}
+
+	want := []string{"Book.Title", "Book.Subtitle"}
+	if len(diags) != len(want) {
+		t.Fatalf("expected %d diagnostics, got %v", len(want), diags)
+	}
+
+	for i, d := range diags {
+		if got := d.Struct + "." + d.Field; got != want[i] {
+			t.Errorf("diagnostic %d is for %s, want %s", i, got, want[i])
+		}
+	}
+
+	// A renamed column of the same type is not paired by position in results, only in params.
+	renamed := StructInfo{Name: "GetBookRow", Fields: []FieldInfo{
+		{Name: "ID", Type: "int64"}, {Name: "Name", Type: "string"}, {Name: "Summary", Type: "string"},
+	}}
+
+	fields, _ = convertStructFields(target, renamed, "res.", false)
+	if got := strings.Join(fields, "\n"); got != "ID: res.ID" {
+		t.Errorf("ConvertStructFields() for a result = %q, want only ID", got)
+	}
+
+	fields, _ = convertStructFields(target, renamed, "arg.", true)
+	if got := strings.Join(fields, "\n"); got != "ID: arg.ID\nTitle: arg.Name\nSubtitle: arg.Summary" {
+		t.Errorf("ConvertStructFields() for params = %q, want positional matches", got)
+	}
+}
+
+func TestBulkLoopFieldsDiagnostics(t *testing.T) {
+	t.Parallel()
+
+	bulk := StructInfo{
+		Name: "AddBookTagsParams",
+		Fields: []FieldInfo{
+			{Name: "BookIds", Type: "[]int64"},
+			{Name: "Labels", Type: "[]string"},
+		},
+	}
+	target := StructInfo{
+		Name: "AddBookTagParams",
+		Fields: []FieldInfo{
+			{Name: "BookID", Type: "int64"},
+			{Name: "TagName", Type: "string"},
+		},
+	}
+
+	fields, diags := bulkLoopFields(target, bulk, bulk.Fields[0], "arg")
+
+	wantFields := []string{"BookID: v", "TagName: arg.Labels[i]"}
+	if strings.Join(fields, "\n") != strings.Join(wantFields, "\n") {
+		t.Errorf("BulkLoopFields() = %q, want %q", fields, wantFields)
+	}
+
+	if len(diags) != 1 || diags[0].Field != "TagName" {
+		t.Fatalf("expected a positional match diagnostic for TagName, got %v", diags)
+	}
+
+	want := "AddBookTags (sqlite): AddBookTagParams.TagName: matched by position to source field Labels"
+
+	diags[0].Method = "AddBookTags"
+	diags[0].Engine = "sqlite"
+
+	if got := diags[0].String(); got != want {
+		t.Errorf("Diagnostic.String() = %q, want %q", got, want)
+	}
+}
+
+func TestValidationReport(t *testing.T) {
+	t.Parallel()
+
+	methods := []MethodInfo{
+		{
+			Name:         "CreateBook",
+			Params:       []Param{{Name: "ctx", Type: "context.Context"}, {Name: "title", Type: "string"}},
+			Returns:      []Return{{Type: "Book"}, {Type: "error"}},
+			ReturnElem:   "Book",
+			ReturnsError: true,
+			HasValue:     true,
+			IsCreate:     true,
+		},
+		{
+			Name:         "DeleteBook",
+			Params:       []Param{{Name: "ctx", Type: "context.Context"}, {Name: "id", Type: "int64"}},
+			Returns:      []Return{{Type: "error"}},
+			ReturnsError: true,
+		},
+		{
+			Name:         "GetBookByID",
+			Params:       []Param{{Name: "ctx", Type: "context.Context"}, {Name: "id", Type: "int64"}},
+			Returns:      []Return{{Type: "Book"}, {Type: "error"}},
+			ReturnElem:   "Book",
+			ReturnsError: true,
+			HasValue:     true,
+			IsSynthetic:  true,
+		},
+		{
+			Name:         "ListBooks",
+			Params:       []Param{{Name: "ctx", Type: "context.Context"}},
+			Returns:      []Return{{Type: "[]Book"}, {Type: "error"}},
+			ReturnElem:   "Book",
+			ReturnsError: true,
+			HasValue:     true,
+		},
+	}
+
+	bookStruct := StructInfo{Name: "Book", Fields: []FieldInfo{{Name: "ID", Type: "int64"}}}
+
+	engines := []Engine{
+		{Name: "sqlite", Package: "sqlitedb"},
+		{Name: "mysql", Package: "mysqldb"},
+	}
+
+	engineData := map[string]PackageData{
+		"sqlite": {
+			Methods: []MethodInfo{
+				{
+					Name:    "CreateBook",
+					Params:  []Param{{Name: "ctx", Type: "context.Context"}, {Name: "title", Type: "string"}},
+					Returns: []Return{{Type: "Book"}, {Type: "error"}},
+				},
+				{
+					Name:    "DeleteBook",
+					Params:  []Param{{Name: "ctx", Type: "context.Context"}, {Name: "id", Type: "int64"}},
+					Returns: []Return{{Type: "error"}},
+				},
+				{
+					Name:    "ListBooks",
+					Params:  []Param{{Name: "ctx", Type: "context.Context"}},
+					Returns: []Return{{Type: "[]Book"}, {Type: "error"}},
+				},
+			},
+			Structs: map[string]StructInfo{"Book": bookStruct},
+		},
+		"mysql": {
+			Methods: []MethodInfo{
+				{
+					Name:    "CreateBook",
+					Params:  []Param{{Name: "ctx", Type: "context.Context"}, {Name: "title", Type: "string"}},
+					Returns: []Return{{Type: "Book"}, {Type: "error"}},
+				},
+				{
+					Name: "DeleteBook",
+					Params: []Param{
+						{Name: "ctx", Type: "context.Context"},
+						{Name: "id", Type: "int64"},
+						{Name: "author", Type: "string"},
+					},
+					Returns: []Return{{Type: "error"}},
+				},
+				{
+					Name:    "ListBooks",
+					Params:  []Param{{Name: "ctx", Type: "context.Context"}},
+					Returns: []Return{{Type: "Book"}, {Type: "error"}},
+				},
+			},
+		},
+	}
+
+	if report := validationReport(methods, nil, engines[:1], engineData); report != "" {
+		t.Errorf("expected consistent sqlite engine, got:\n%s", report)
+	}
+
+	report := validationReport(methods, nil, engines, engineData)
+
+	for _, want := range []string{
+		"6 engine consistency problem(s)",
+		"CreateBook: model struct mysqldb.Book is missing",
+		"CreateBook: returns Book but MySQL RETURNING emulation needs a :execresult query (sql.Result)",
+		"DeleteBook: takes 2 parameter(s) but the engine method takes 3",
+		"GetBookByID: model struct mysqldb.Book is missing",
+		"ListBooks: returns []Book, error (slice) but the engine method returns Book, error (value)",
+	} {
+		if !strings.Contains(report, want) {
+			t.Errorf("expected report to contain %q, got:\n%s", want, report)
+		}
+	}
+
+	if strings.Contains(report, "sqlite:") {
+		t.Errorf("expected no sqlite problems, got:\n%s", report)
+	}
+}
+
+func TestExtractEngineList(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		comment    string
+		annotation string
+		want       []string
+	}{
+		{"// @engines postgres", "@engines", []string{"postgres"}},
+		{"// @engines postgres,mysql", "@engines", []string{"postgres", "mysql"}},
+		{"// @skip-engine sqlite", "@skip-engine", []string{"sqlite"}},
+		{"// @skip-engine sqlite", "@engines", nil},
+		{"// @engines", "@engines", nil},
+	}
+
+	for _, tt := range tests {
+		got := extractEngineList(tt.comment, tt.annotation)
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("ExtractEngineList(%q, %q) = %v, want %v", tt.comment, tt.annotation, got, tt.want)
+		}
+	}
+}
+
+func TestWrapperTemplateNotSupportedStub(t *testing.T) {
+	t.Parallel()
+
+	methods := []MethodInfo{
+		{
+			Name: "SearchBooks",
+			Params: []Param{
+				{Name: "ctx", Type: "context.Context"},
+				{Name: "query", Type: "string"},
+			},
+			Returns:      []Return{{Type: "[]int64"}, {Type: "error"}},
+			ReturnElem:   "int64",
+			ReturnsError: true,
+			HasValue:     true,
+			Engines:      []string{"postgres"},
+		},
+	}
+
+	structs := map[string]StructInfo{}
+
+	output := renderWrapper(t, Engine{Name: "sqlite", Package: "sqlitedb"}, methods, structs, PackageData{})
+	if !strings.Contains(output, "return nil, ErrNotSupported") {
+		t.Errorf("expected sqlite stub to return ErrNotSupported, got\n%s", output)
+	}
+
+	if strings.Contains(output, "w.adapter.SearchBooks") {
+		t.Errorf("expected sqlite stub not to call the adapter, got\n%s", output)
+	}
+
+	engData := PackageData{Methods: []MethodInfo{methods[0]}}
+
+	output = renderWrapper(t, Engine{Name: "postgres", Package: "postgresdb"}, methods, structs, engData)
+	if !strings.Contains(output, "w.adapter.SearchBooks(ctx, query)") {
+		t.Errorf("expected postgres wrapper to call the adapter, got\n%s", output)
+	}
+
+	// A stub without an error return could only panic, so it is rejected up front.
+	noError := []MethodInfo{{
+		Name:        "Vacuum",
+		Params:      []Param{{Name: "ctx", Type: "context.Context"}},
+		SkipEngines: []string{"sqlite"},
+	}}
+
+	report := validationReport(noError, structs, []Engine{{Name: "sqlite", Package: "sqlitedb"}}, nil)
+	if want := "Vacuum: is not supported here but has no error return"; !strings.Contains(report, want) {
+		t.Errorf("expected report to contain %q, got:\n%s", want, report)
+	}
+
+	noError[0].SkipEngines, noError[0].EngineOnly = nil, []string{"postgres"}
+	engines := []Engine{{Name: "sqlite", Package: "sqlitedb"}, {Name: "postgres", Package: "postgresdb"}}
+	engineData := map[string]PackageData{"postgres": {Methods: noError}}
+
+	if report := validationReport(noError, structs, engines, engineData); report != "" {
+		t.Errorf("expected @engine-only methods without an error return to be accepted, got:\n%s", report)
+	}
+}
+
+func TestExtensions(t *testing.T) {
+	t.Parallel()
+
+	postgres := Engine{Name: "postgres", Package: "postgresdb"}
+	sqlite := Engine{Name: "sqlite", Package: "sqlitedb"}
+
+	methods := []MethodInfo{
+		{Name: "GetBook"},
+		{Name: "SearchBooks", EngineOnly: []string{"postgres"}},
+	}
+
+	exts := extensions(methods, []Engine{sqlite, postgres})
+	if len(exts) != 1 {
+		t.Fatalf("expected one extension, got %d", len(exts))
+	}
+
+	if got := exts[0].InterfaceName(); got != "PostgresQuerier" {
+		t.Errorf("InterfaceName() = %q, want %q", got, "PostgresQuerier")
+	}
+
+	if len(exts[0].Methods) != 1 || exts[0].Methods[0].Name != "SearchBooks" {
+		t.Errorf("expected SearchBooks on PostgresQuerier, got %v", exts[0].Methods)
+	}
+
+	if got := wrapperMethods(methods, sqlite); len(got) != 1 || got[0].Name != "GetBook" {
+		t.Errorf("expected the sqlite wrapper to skip SearchBooks, got %v", got)
+	}
+
+	pgMethods := wrapperMethods(methods, postgres)
+	if len(pgMethods) != 2 {
+		t.Errorf("expected the postgres wrapper to implement both methods, got %v", pgMethods)
+	}
+
+	output := renderWrapper(t, postgres, pgMethods[1:], map[string]StructInfo{}, PackageData{})
+	if !strings.Contains(output, "var _ PostgresQuerier = (*postgresWrapper)(nil)") {
+		t.Errorf("expected postgres wrapper to assert PostgresQuerier, got\n%s", output)
+	}
+}
+
+func TestSelectMethods(t *testing.T) {
+	t.Parallel()
+
+	ctxParam := Param{Name: "ctx", Type: "context.Context"}
+	errReturn := Return{Type: "error"}
+
+	source := PackageData{
+		Methods: []MethodInfo{
+			{Name: "DeleteBook", Params: []Param{ctxParam}, Returns: []Return{errReturn}, ReturnsError: true},
+			{Name: "ListBooks", Params: []Param{ctxParam}, Returns: []Return{errReturn}, ReturnsError: true},
+		},
+		Structs: map[string]StructInfo{},
+	}
+
+	searchStruct := StructInfo{Name: "SearchResult", Fields: []FieldInfo{{Name: "ID", Type: "int64"}}}
+
+	engines := []Engine{
+		{Name: "postgres", Package: "postgresdb"},
+		{Name: "sqlite", Package: "sqlitedb"},
+		{Name: "mysql", Package: "mysqldb"},
+	}
+
+	engineData := map[string]PackageData{
+		"postgres": {Methods: source.Methods},
+		"sqlite": {
+			Methods: []MethodInfo{
+				source.Methods[0],
+				{
+					Name:         "SearchBooks",
+					Params:       []Param{ctxParam, {Name: "query", Type: "string"}},
+					Returns:      []Return{{Type: "[]SearchResult"}, errReturn},
+					ReturnElem:   "SearchResult",
+					ReturnsError: true,
+					HasValue:     true,
+				},
+			},
+			Structs: map[string]StructInfo{"SearchResult": searchStruct},
+		},
+		"mysql": {
+			Methods: []MethodInfo{
+				source.Methods[0],
+				source.Methods[1],
+				{
+					Name:         "SearchBooks",
+					Params:       []Param{ctxParam},
+					Returns:      []Return{{Type: "SearchResult"}, errReturn},
+					ReturnsError: true,
+					HasValue:     true,
+				},
+			},
+		},
+	}
+
+	t.Run("union", func(t *testing.T) {
+		t.Parallel()
+
+		data, report := selectMethodsReport(source, engines, engineData, MethodSetUnion)
+
+		if len(data.Methods) != 3 || data.Methods[2].Name != "SearchBooks" {
+			t.Fatalf("expected SearchBooks to be added, got %+v", data.Methods)
+		}
+
+		if got := strings.Join(data.Methods[2].Engines, ","); got != "sqlite,mysql" {
+			t.Errorf("expected SearchBooks restricted to sqlite,mysql, got %q", got)
+		}
+
+		if _, ok := data.Structs["SearchResult"]; !ok {
+			t.Error("expected SearchResult to be copied from sqlitedb")
+		}
+
+		if _, ok := source.Structs["SearchResult"]; ok {
+			t.Error("expected the source structs to be left untouched")
+		}
+
+		expected := "2 engine consistency problem(s), no files were written:" +
+			"\n  mysql:" +
+			"\n    - SearchBooks: conflicts with sqlite: takes 2 parameter(s) but the engine method takes 1" +
+			"\n    - SearchBooks: conflicts with sqlite: returns []SearchResult, error (slice)" +
+			" but the engine method returns SearchResult, error (value)"
+		if report != expected {
+			t.Errorf("unexpected report:\n%s\nexpected:\n%s", report, expected)
+		}
+	})
+
+	t.Run("intersection", func(t *testing.T) {
+		t.Parallel()
+
+		data, report := selectMethodsReport(source, engines, engineData, MethodSetIntersection)
+
+		if report != "" {
+			t.Errorf("expected no report, got %q", report)
+		}
+
+		if len(data.Methods) != 1 || data.Methods[0].Name != "DeleteBook" {
+			t.Errorf("expected only DeleteBook, got %+v", data.Methods)
+		}
+	})
+
+	t.Run("source", func(t *testing.T) {
+		t.Parallel()
+
+		data, _ := selectMethodsReport(source, engines, engineData, MethodSetSource)
+		if len(data.Methods) != 2 {
+			t.Errorf("expected the source methods unchanged, got %+v", data.Methods)
+		}
+	})
+}
+
+func TestParseMultiRowInsert(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		query string
+		want  multiRowInsert
+		ok    bool
+	}{
+		{
+			query: "INSERT INTO book_tags (`book_id`, `tag_id`) VALUES (?, ?)",
+			want: multiRowInsert{
+				Prefix:       "INSERT INTO book_tags (`book_id`, `tag_id`) VALUES ",
+				Row:          "(?, ?)",
+				Placeholders: 2,
+			},
+			ok: true,
+		},
+		{
+			query: "INSERT INTO tags (name, slug) VALUES (?, lower(?)) ON CONFLICT DO NOTHING;",
+			want: multiRowInsert{
+				Prefix:       "INSERT INTO tags (name, slug) VALUES ",
+				Row:          "(?, lower(?))",
+				Suffix:       " ON CONFLICT DO NOTHING",
+				Placeholders: 2,
+			},
+			ok: true,
+		},
+		{query: `INSERT INTO books ("title") VALUES (?) RETURNING "id"`},
+		{query: "INSERT INTO tags (name) VALUES (?1)"},
+		{query: "INSERT INTO tags (name) VALUES ($1)"},
+		{query: "INSERT INTO tags (name) VALUES (?), (?)"},
+		{query: "INSERT INTO tags (name) VALUES (?) ON DUPLICATE KEY UPDATE name = ?"},
+		{query: "UPDATE tags SET name = ? WHERE id = ?"},
+	}
+
+	for _, tt := range tests {
+		got, ok := parseMultiRowInsert(tt.query)
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("parseMultiRowInsert(%q) = (%+v, %v), expected (%+v, %v)", tt.query, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestWrapperTemplateMultiRowInsert(t *testing.T) {
+	t.Parallel()
+
+	methods := []MethodInfo{
+		{
+			Name: "AddBookTags",
+			Params: []Param{
+				{Name: "ctx", Type: "context.Context"},
+				{Name: "arg", Type: "AddBookTagsParams"},
+			},
+			Returns:      []Return{{Type: "error"}},
+			ReturnsError: true,
+			BulkFor:      "AddBookTag",
+		},
+	}
+
+	structs := map[string]StructInfo{
+		"AddBookTagsParams": {Name: "AddBookTagsParams", Fields: []FieldInfo{
+			{Name: "BookIds", Type: "[]int64"},
+			{Name: "TagIds", Type: "[]int64"},
+		}},
+	}
+
+	addBookTag := MethodInfo{
+		Name: "AddBookTag",
+		Params: []Param{
+			{Name: "ctx", Type: "context.Context"},
+			{Name: "arg", Type: "AddBookTagParams"},
+		},
+		Returns: []Return{{Type: "error"}},
+		Docs: []string{
+			"// INSERT INTO book_tags (`book_id`, `tag_id`) VALUES (?, ?)",
+			"//",
+			"//  INSERT INTO book_tags (`book_id`, `tag_id`)",
+			"//  VALUES (?, ?)",
+		},
+	}
+
+	engData := PackageData{
+		Methods: []MethodInfo{addBookTag},
+		Structs: map[string]StructInfo{
+			"AddBookTagParams": {Name: "AddBookTagParams", Fields: []FieldInfo{
+				{Name: "BookID", Type: "int64"},
+				{Name: "TagID", Type: "int64"},
+			}},
+		},
+	}
+
+	mysql := Engine{Name: "mysql", Package: "mysqldb"}
+
+	output := renderWrapper(t, mysql, methods, structs, engData)
+
+	for _, want := range []string{
+		"chunkSize := mysqlMaxPlaceholders / rowPlaceholders",
+		"args = append(args, row.BookID, row.TagID)",
+		`query := "INSERT INTO book_tags (` + "`book_id`, `tag_id`" + `) VALUES " + "(?, ?)" +`,
+		`strings.Repeat(", (?, ?)", end-start-1)`,
+		"a.DBTX().ExecContext(ctx, query, args...)",
+		`return &BulkError{Method: "AddBookTags", Index: start, Err: err}`,
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected output to contain %q\n%s", want, output)
+		}
+	}
+
+	// Without the singular query's SQL the wrapper falls back to the per-element loop.
+	addBookTag.Docs = nil
+	engData.Methods = []MethodInfo{addBookTag}
+
+	output = renderWrapper(t, mysql, methods, structs, engData)
+	if !strings.Contains(output, "err := a.AddBookTag(ctx, mysqldb.AddBookTagParams{") {
+		t.Errorf("expected the per-element loop without the singular SQL\n%s", output)
+	}
+}
+
+func TestWrapperTemplateBulkCollectsResults(t *testing.T) {
+	t.Parallel()
+
+	ctxParam := Param{Name: "ctx", Type: "context.Context"}
+
+	methods := []MethodInfo{
+		{
+			Name:         "CreateTag",
+			Params:       []Param{ctxParam, {Name: "arg", Type: "CreateTagParams"}},
+			Returns:      []Return{{Type: "Tag"}, {Type: "error"}},
+			ReturnElem:   "Tag",
+			ReturnsError: true,
+			HasValue:     true,
+			IsCreate:     true,
+		},
+		{
+			Name:         "CreateTags",
+			Params:       []Param{ctxParam, {Name: "arg", Type: "CreateTagsParams"}},
+			Returns:      []Return{{Type: "[]Tag"}, {Type: "error"}},
+			ReturnElem:   "Tag",
+			ReturnsError: true,
+			HasValue:     true,
+			IsCreate:     true,
+			BulkFor:      "CreateTag",
+		},
+	}
+
+	structs := map[string]StructInfo{
+		"CreateTagParams": {Name: "CreateTagParams", Fields: []FieldInfo{
+			{Name: "Name", Type: "string"},
+			{Name: "Color", Type: "string"},
+		}},
+		"CreateTagsParams": {Name: "CreateTagsParams", Fields: []FieldInfo{
+			{Name: "Names", Type: "[]string"},
+			{Name: "Colors", Type: "[]string"},
+		}},
+		"Tag": {Name: "Tag", Fields: []FieldInfo{{Name: "ID", Type: "int64"}, {Name: "Name", Type: "string"}}},
+	}
+
+	output := renderWrapper(t, Engine{Name: "mysql", Package: "mysqldb"}, methods, structs, PackageData{})
+
+	for _, want := range []string{
+		"var items []Tag",
+		"tw := &mysqlWrapper{adapter: a}",
+		"item, err := tw.CreateTag(ctx, CreateTagParams{",
+		"Name: v,",
+		"Color: arg.Colors[i],",
+		"items = append(items, item)",
+		"return items, nil",
+		`return &BulkError{Method: "CreateTags", Index: i, Err: err}`,
+		"if err := ctx.Err(); err != nil {",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected output to contain %q\n%s", want, output)
+		}
+	}
+
+	if strings.Contains(output, "w.adapter.CreateTags") {
+		t.Errorf("expected the engine's bulk method not to be called\n%s", output)
+	}
+
+	// With --query-errors, the bulk method's QueryError wraps the BulkError directly.
+	mysql := Engine{Name: "mysql", Package: "mysqldb"}
+	tmpl := template.Must(template.New("wrapper").
+		Funcs(wrapperFuncMap(mysql, methods, structs, PackageData{}, nil)).
+		Parse(wrapperTemplate))
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, map[string]interface{}{
+		"Engine": mysql, "Methods": methods, "Structs": structs, "QueryErrors": true,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `return &BulkError{Method: "CreateTags", Index: i, Err: unwrapQueryError(err)}`
+	if !strings.Contains(buf.String(), want) {
+		t.Errorf("expected output to contain %q\n%s", want, buf.String())
+	}
+
+	// A singular method taking a single value cannot receive the other slice fields.
+	methods[0].Params = []Param{ctxParam, {Name: "name", Type: "string"}}
+	engData := map[string]PackageData{"mysql": {Methods: methods}}
+
+	report := validationReport(methods, structs, []Engine{mysql}, engData)
+	want = "@bulk-for CreateTag takes a single value, " +
+		"so only CreateTagsParams.Names reaches it and Colors would be dropped"
+	if !strings.Contains(report, want) {
+		t.Errorf("expected report to contain %q, got:\n%s", want, report)
+	}
+}
+
+func TestWrapperTemplateBulkChunk(t *testing.T) {
+	t.Parallel()
+
+	methods := []MethodInfo{
+		{
+			Name: "CreateTags",
+			Params: []Param{
+				{Name: "ctx", Type: "context.Context"},
+				{Name: "arg", Type: "CreateTagsParams"},
+			},
+			Returns:      []Return{{Type: "[]Tag"}, {Type: "error"}},
+			ReturnElem:   "Tag",
+			ReturnsError: true,
+			HasValue:     true,
+			BulkFor:      "CreateTag",
+			BulkChunk:    500,
+		},
+	}
+
+	structs := map[string]StructInfo{
+		"CreateTagsParams": {Name: "CreateTagsParams", Fields: []FieldInfo{
+			{Name: "Names", Type: "[]string"},
+			{Name: "Colors", Type: "[]string"},
+			{Name: "Owner", Type: "string"},
+		}},
+		"Tag": {Name: "Tag", Fields: []FieldInfo{{Name: "ID", Type: "int64"}}},
+	}
+
+	output := renderWrapper(t, Engine{Name: "postgres", Package: "postgresdb"}, methods, structs, PackageData{})
+
+	for _, want := range []string{
+		"return nil, ErrMismatchedSlices",
+		"if len(arg.Names) > 500 {",
+		"for start := 0; start < len(arg.Names); start += 500 {",
+		"chunk.Names = arg.Names[start:end]",
+		"chunk.Colors = arg.Colors[start:end]",
+		"res, err := tw.CreateTags(ctx, chunk)",
+		"items = append(items, res...)",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected output to contain %q\n%s", want, output)
+		}
+	}
+
+	if strings.Contains(output, "chunk.Owner") {
+		t.Errorf("expected scalar fields to be passed through unchanged\n%s", output)
+	}
+}
+
+func TestWrapperTemplateJSONBulk(t *testing.T) {
+	t.Parallel()
+
+	methods := []MethodInfo{
+		{
+			Name: "AddBookTags",
+			Params: []Param{
+				{Name: "ctx", Type: "context.Context"},
+				{Name: "arg", Type: "AddBookTagsParams"},
+			},
+			Returns:      []Return{{Type: "error"}},
+			ReturnsError: true,
+			BulkFor:      "AddBookTag",
+			BulkStrategy: "json",
+		},
+	}
+
+	structs := map[string]StructInfo{
+		"AddBookTagsParams": {Name: "AddBookTagsParams", Fields: []FieldInfo{
+			{Name: "BookIds", Type: "[]int64"},
+			{Name: "TagIds", Type: "[]int64"},
+			{Name: "Source", Type: "string"},
+		}},
+	}
+
+	addBookTags := MethodInfo{
+		Name: "AddBookTags",
+		Params: []Param{
+			{Name: "ctx", Type: "context.Context"},
+			{Name: "arg", Type: "AddBookTagsParams"},
+		},
+		Returns: []Return{{Type: "error"}},
+	}
+
+	engData := PackageData{
+		Methods: []MethodInfo{addBookTags},
+		Structs: map[string]StructInfo{
+			"AddBookTagsParams": {Name: "AddBookTagsParams", Fields: []FieldInfo{
+				{Name: "BookIds", Type: "string"},
+				{Name: "TagIds", Type: "[]byte"},
+				{Name: "Source", Type: "string"},
+			}},
+		},
+	}
+
+	output := renderWrapper(t, Engine{Name: "sqlite", Package: "sqlitedb"}, methods, structs, engData)
+
+	for _, want := range []string{
+		"return ErrMismatchedSlices",
+		"bookIdsJSON, err := json.Marshal(arg.BookIds)",
+		"tagIdsJSON, err := json.Marshal(arg.TagIds)",
+		"return w.adapter.AddBookTags(ctx, sqlitedb.AddBookTagsParams{",
+		"BookIds: string(bookIdsJSON)",
+		"TagIds: tagIdsJSON",
+		"Source: arg.Source",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected output to contain %q\n%s", want, output)
+		}
+	}
+
+	if strings.Contains(output, "runBulk(ctx, func") {
+		t.Errorf("expected a single statement, not an auto-loop\n%s", output)
+	}
+
+	// A JSON array cannot be passed to an engine parameter of another type.
+	engData.Structs["AddBookTagsParams"].Fields[0].Type = "int64"
+
+	tmpl := template.Must(template.New("wrapper").
+		Funcs(wrapperFuncMap(Engine{Name: "sqlite", Package: "sqlitedb"}, methods, structs, engData, nil)).
+		Parse(wrapperTemplate))
+
+	err := tmpl.Execute(io.Discard, map[string]interface{}{
+		"Engine":  Engine{Name: "sqlite", Package: "sqlitedb"},
+		"Methods": methods,
+		"Structs": structs,
+	})
+	if err == nil || !strings.Contains(err.Error(), "parameter BookIds is int64") {
+		t.Errorf("expected an error for the int64 engine parameter, got %v", err)
+	}
+}
+
+func TestInferBulkFor(t *testing.T) {
+	t.Parallel()
+
+	argParam := func(t string) []Param {
+		return []Param{{Name: "ctx", Type: "context.Context"}, {Name: "arg", Type: t}}
+	}
+
+	methods := []MethodInfo{
+		{Name: "CreateUser", Params: argParam("CreateUserParams")},
+		{Name: "CreateUsers", Params: argParam("CreateUsersParams")},
+		{Name: "UpdateStatus", Params: argParam("UpdateStatusParams")},
+		{Name: "AddTags", Params: argParam("AddTagsParams"), BulkFor: "AddTagToBook"},
+	}
+
+	structs := map[string]StructInfo{
+		"CreateUserParams":   {Name: "CreateUserParams", Fields: []FieldInfo{{Name: "Name", Type: "string"}}},
+		"CreateUsersParams":  {Name: "CreateUsersParams", Fields: []FieldInfo{{Name: "Names", Type: "[]string"}}},
+		"UpdateStatuParams":  {Name: "UpdateStatuParams", Fields: []FieldInfo{{Name: "ID", Type: "int64"}}},
+		"UpdateStatusParams": {Name: "UpdateStatusParams", Fields: []FieldInfo{{Name: "IDs", Type: "[]int64"}}},
+		"AddTagsParams":      {Name: "AddTagsParams", Fields: []FieldInfo{{Name: "TagIDs", Type: "[]int64"}}},
+	}
+
+	bulkFor := func(methods []MethodInfo) string {
+		var parts []string
+		for _, m := range methods {
+			parts = append(parts, m.Name+"="+m.BulkFor)
+		}
+
+		return strings.Join(parts, " ")
+	}
+
+	tests := []struct {
+		name string
+		opts Options
+		want string
+	}{
+		{
+			name: "auto",
+			opts: Options{},
+			want: "CreateUser= CreateUsers=CreateUser UpdateStatus= AddTags=AddTagToBook",
+		},
+		{
+			name: "auto in strict mode",
+			opts: Options{Strict: true},
+			want: "CreateUser= CreateUsers= UpdateStatus= AddTags=AddTagToBook",
+		},
+		{
+			name: "on in strict mode",
+			opts: Options{Strict: true, InferBulk: BulkInferenceOn},
+			want: "CreateUser= CreateUsers=CreateUser UpdateStatus= AddTags=AddTagToBook",
+		},
+		{
+			name: "off",
+			opts: Options{InferBulk: BulkInferenceOff},
+			want: "CreateUser= CreateUsers= UpdateStatus= AddTags=AddTagToBook",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			inferred := methods
+			if tt.opts.inferBulk() {
+				inferred = inferBulkFor(methods, structs)
+			}
+
+			if got := bulkFor(inferred); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if methods[1].BulkFor != "" {
+		t.Error("expected the input methods to be left untouched")
+	}
+}
+
+func TestGenerateAdapter(t *testing.T) {
+	t.Parallel()
+
+	adapter := string(generateAdapter(Engine{Name: "mysql", Package: "mysqldb"}))
+
+	for _, want := range []string{
+		"package mysqldb",
+		"// NewAdapter creates a new MySQL adapter.",
+		"func (a *Adapter) WithTx(tx *sql.Tx) *Adapter {",
+		"func (a *Adapter) DBTX() DBTX {",
+		"func (a *Adapter) WithDBTX(db DBTX) *Adapter {",
+	} {
+		if !strings.Contains(adapter, want) {
+			t.Errorf("expected adapter to contain %q\n%s", want, adapter)
+		}
+	}
+
+	dir := t.TempDir()
+
+	write := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("db.go", "package mysqldb\n\ntype Queries struct{}\n\nfunc (q *Queries) Adapter() {}\n")
+	write("generated_adapter.go", "package mysqldb\n\ntype Adapter struct{}\n")
+
+	if declaresIdent(dir, "Adapter", "generated_adapter.go") {
+		t.Error("expected a method and the skipped file not to count as declarations")
+	}
+
+	write("adapter.go", "package mysqldb\n\ntype Adapter struct{ *Queries }\n")
+
+	if !declaresIdent(dir, "Adapter", "generated_adapter.go") {
+		t.Error("expected the hand-written Adapter to be found")
+	}
+}
+
+func TestGenerateOpen(t *testing.T) {
+	t.Parallel()
+
+	engines := []Engine{
+		{Name: "sqlite", Package: "sqlitedb"},
+		{Name: "postgres", Package: "postgresdb"},
+	}
+
+	open := string(generateOpen("database", engines, true))
+
+	for _, want := range []string{
+		"//go:build !js",
+		`_ "github.com/mattn/go-sqlite3"`,
+		`_ "github.com/jackc/pgx/v5/stdlib"`,
+		`var ErrUnsupportedDriver = errors.New("unsupported database driver")`,
+		`case strings.HasPrefix(dbURL, "sqlite:"):`,
+		`case strings.HasPrefix(dbURL, "postgres://"), strings.HasPrefix(dbURL, "postgresql://"):`,
+		`return o.openSQLite(ctx, strings.TrimPrefix(dbURL, "sqlite:"))`,
+		"sdb, err := sql.Open(o.postgresDriver, dbURL)",
+		"return NewSQLiteQuerierWithReader(writer, reader), nil",
+		"func WithSQLitePragmas(pragmas ...string) Option {",
+		"func WithPostgresDriver(name string) Option {",
+		"return NewPostgresQuerier(sdb), nil",
+	} {
+		if !strings.Contains(open, want) {
+			t.Errorf("expected Open to contain %q\n%s", want, open)
+		}
+	}
+
+	for _, unwanted := range []string{"go-sql-driver/mysql", "NewMySQLQuerier", "WithMySQLDriver"} {
+		if strings.Contains(open, unwanted) {
+			t.Errorf("expected Open not to reference the unconfigured engine via %q", unwanted)
+		}
+	}
+
+	open = string(generateOpen("database", engines[:1], false))
+
+	if strings.Contains(open, "var ErrUnsupportedDriver") || strings.Contains(open, "go:build") {
+		t.Errorf("expected a SQLite-only Open without build tag or sentinel\n%s", open)
+	}
+}
+
+func TestWrapperTemplateSQLiteReader(t *testing.T) {
+	t.Parallel()
+
+	for query, want := range map[string]bool{
+		"SELECT id, name FROM users WHERE id = ?":                             true,
+		"WITH recent AS (SELECT id FROM users) SELECT updated_at FROM recent": true,
+		"INSERT INTO users (name) VALUES (?) RETURNING id, name":              false,
+		"WITH gone AS (DELETE FROM users RETURNING id) SELECT id FROM gone":   false,
+		"UPDATE users SET name = ? WHERE id = ?":                              false,
+		"SELECT id FROM users WHERE id = $1 FOR UPDATE":                       false,
+		"SELECT id FROM users WHERE id = $1 FOR NO KEY UPDATE":                false,
+		"SELECT id FROM users WHERE id = $1 FOR SHARE":                        false,
+		"SELECT id FROM users WHERE id = $1 FOR KEY SHARE":                    false,
+		"SELECT id FROM users WHERE id = ? LOCK IN SHARE MODE":                false,
+		"SELECT COUNT(*), COALESCE(MAX(id), 0) FROM users WHERE id IN ($1)":   true,
+		"SELECT nextval('users_id_seq')":                                      false,
+		"SELECT pg_advisory_lock($1)":                                         false,
+	} {
+		if got := isReadOnlyQuery(query); got != want {
+			t.Errorf("IsReadOnlyQuery(%q) = %v, want %v", query, got, want)
+		}
+	}
+
+	getUser := MethodInfo{
+		Name:         "GetUser",
+		Params:       []Param{{Name: "ctx", Type: "context.Context"}, {Name: "id", Type: "int64"}},
+		Returns:      []Return{{Type: "string"}, {Type: "error"}},
+		ReturnElem:   "string",
+		ReturnsError: true,
+		HasValue:     true,
+	}
+	deleteUser := MethodInfo{
+		Name:         "DeleteUserReturningName",
+		Params:       []Param{{Name: "ctx", Type: "context.Context"}, {Name: "id", Type: "int64"}},
+		Returns:      []Return{{Type: "string"}, {Type: "error"}},
+		ReturnElem:   "string",
+		ReturnsError: true,
+		HasValue:     true,
+	}
+
+	getUserFresh := getUser
+	getUserFresh.Name = "GetUserFresh"
+	getUserFresh.Primary = true
+
+	getUserByID := MethodInfo{
+		Name:         "GetUserByID",
+		Params:       []Param{{Name: "ctx", Type: "context.Context"}, {Name: "id", Type: "int64"}},
+		Returns:      []Return{{Type: "User"}, {Type: "error"}},
+		ReturnElem:   "User",
+		ReturnsError: true,
+		HasValue:     true,
+		IsSynthetic:  true,
+	}
+
+	methods := []MethodInfo{deleteUser, getUser, getUserFresh, getUserByID}
+
+	readOnly := getUser
+	readOnly.IsReadOnly = true
+
+	readOnlyFresh := getUserFresh
+	readOnlyFresh.IsReadOnly = true
+
+	engData := PackageData{
+		Methods: []MethodInfo{deleteUser, readOnly, readOnlyFresh},
+		Structs: map[string]StructInfo{
+			"User": {Name: "User", Fields: []FieldInfo{{Name: "ID", Type: "int64"}}},
+		},
+	}
+
+	output := renderWrapper(t, Engine{Name: "sqlite", Package: "sqlitedb"}, methods, nil, engData)
+
+	for _, want := range []string{
+		"reader  *sqlitedb.Adapter",
+		"func NewSQLiteQuerierWithReader(writer, reader *sql.DB) Querier {",
+		"res, err := w.readAdapter().GetUser(ctx, id)",
+		"res, err := w.adapter.DeleteUserReturningName(ctx, id)",
+		// @primary and the generated GetByID lookups stay on the writer, like in RoutingQuerier.
+		"res, err := w.adapter.GetUserFresh(ctx, id)",
+		"row := w.adapter.DBTX().QueryRowContext(ctx, query, id)",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected output to contain %q\n%s", want, output)
+		}
+	}
+
+	output = renderWrapper(t, Engine{Name: "postgres", Package: "postgresdb"}, methods, nil, engData)
+
+	if strings.Contains(output, "readAdapter") || !strings.Contains(output, "res, err := w.adapter.GetUser(ctx, id)") {
+		t.Errorf("expected PostgreSQL reads to use the single adapter\n%s", output)
+	}
+}
+
+func TestWrapperTemplateClassifiesErrors(t *testing.T) {
+	t.Parallel()
+
+	deleteUser := MethodInfo{
+		Name:         "DeleteUser",
+		Params:       []Param{{Name: "ctx", Type: "context.Context"}, {Name: "id", Type: "int64"}},
+		Returns:      []Return{{Type: "error"}},
+		ReturnsError: true,
+	}
+	getName := MethodInfo{
+		Name:         "GetName",
+		Params:       []Param{{Name: "ctx", Type: "context.Context"}, {Name: "id", Type: "int64"}},
+		Returns:      []Return{{Type: "string"}, {Type: "error"}},
+		ReturnElem:   "string",
+		ReturnsError: true,
+		HasValue:     true,
+	}
+
+	methods := []MethodInfo{deleteUser, getName}
+	engData := PackageData{Methods: methods}
+
+	for engine, want := range map[string][]string{
+		"sqlite":   {`strings.Contains(msg, "FOREIGN KEY constraint failed")`},
+		"postgres": {"var pgErr interface{ SQLState() string }", `case "40001": // serialization_failure`},
+		"mysql":    {"number, ok := mysqlErrorNumber(err)", "case 1062, 1586:"},
+	} {
+		e := Engine{Name: engine, Package: engine + "db"}
+		output := renderWrapper(t, e, methods, nil, engData)
+
+		want = append(want,
+			"func (w *"+engine+"Wrapper) DeleteUser(ctx context.Context, id int64) (err error) {",
+			"func (w *"+engine+"Wrapper) GetName(ctx context.Context, id int64) (_ string, err error) {",
+			"defer func() { err = classify"+e.ExportedName()+"Error(err) }()",
+		)
+
+		for _, w := range want {
+			if !strings.Contains(output, w) {
+				t.Errorf("%s: expected output to contain %q\n%s", engine, w, output)
+			}
+		}
+
+		// Classifying must not tie the generated code to a driver (or to cgo).
+		for _, driver := range []string{
+			`"github.com/mattn/go-sqlite3"`, `"github.com/jackc/pgx/v5/pgconn"`, `"github.com/go-sql-driver/mysql"`,
+		} {
+			if strings.Contains(output, driver) {
+				t.Errorf("%s: expected no import of %s\n%s", engine, driver, output)
+			}
+		}
+	}
+
+	output := renderWrapper(t, Engine{Name: "oracle", Package: "oracledb"}, methods, nil, engData)
+
+	if !strings.Contains(output, "func classifyOracleError(err error) error {\n\n\treturn err\n}") {
+		t.Errorf("expected an engine without a classifier to return errors unchanged\n%s", output)
+	}
+}
+
+func TestGenerateMySQLErrors(t *testing.T) {
+	t.Parallel()
+
+	output := string(formatFile(mysqlErrorsFileName, generateMySQLErrors("database", true)))
+
+	for _, want := range []string{
+		"//go:build !nomysqldriver",
+		`"github.com/go-sql-driver/mysql"`,
+		"var mysqlErr *mysql.MySQLError\n\tif errors.As(err, &mysqlErr) {\n\t\treturn mysqlErr.Number, true",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected driver variant to contain %q\n%s", want, output)
+		}
+	}
+
+	// Only the driver's own error type is classified, never a message that merely looks like one.
+	if strings.Contains(output, "Sscanf") {
+		t.Errorf("expected no parsing of error messages\n%s", output)
+	}
+
+	output = string(formatFile(mysqlNoDriverErrorsFileName, generateMySQLErrors("database", false)))
+
+	if !strings.Contains(output, "//go:build nomysqldriver") ||
+		!strings.Contains(output, "func mysqlErrorNumber(error) (uint16, bool) { return 0, false }") {
+		t.Errorf("expected the nomysqldriver variant to classify nothing\n%s", output)
+	}
+
+	if strings.Contains(output, `"github.com/go-sql-driver/mysql"`) {
+		t.Errorf("expected the nomysqldriver variant not to import the driver\n%s", output)
+	}
+}
+
+func TestWrapperTemplateQueryErrors(t *testing.T) {
+	t.Parallel()
+
+	methods := []MethodInfo{{
+		Name:         "DeleteUser",
+		Params:       []Param{{Name: "ctx", Type: "context.Context"}, {Name: "id", Type: "int64"}},
+		Returns:      []Return{{Type: "error"}},
+		ReturnsError: true,
+		EngineOnly:   []string{"postgres"},
+	}}
+
+	for _, tc := range []struct {
+		engine Engine
+		want   string
+	}{
+		{
+			Engine{Name: "sqlite", Package: "sqlitedb"},
+			`defer func() { err = newQueryError("sqlite", "DeleteUser", classifySQLiteError(err)) }()`,
+		},
+		{
+			Engine{Name: "oracle", Package: "oracledb"},
+			`defer func() { err = newQueryError("oracle", "DeleteUser", classifyOracleError(err)) }()`,
+		},
+	} {
+		tmpl := template.Must(template.New("wrapper").
+			Funcs(wrapperFuncMap(tc.engine, methods, nil, PackageData{}, nil)).
+			Parse(wrapperTemplate))
+
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, map[string]interface{}{
+			"Engine":      tc.engine,
+			"Methods":     methods,
+			"QueryErrors": true,
+		}); err != nil {
+			t.Fatal(err)
+		}
+
+		output := buf.String()
+
+		// The defer precedes the ErrNotSupported return, which is wrapped as well.
+		idx := strings.Index(output, tc.want)
+		if idx == -1 || idx > strings.Index(output, "return ErrNotSupported") {
+			t.Errorf("expected %q before the ErrNotSupported return\n%s", tc.want, output)
+		}
+	}
+}
+
+func TestWrapperTemplateQueryErrorsMySQLReturning(t *testing.T) {
+	t.Parallel()
+
+	mysql := Engine{Name: "mysql", Package: "mysqldb"}
+	book := StructInfo{Name: "Book", Fields: []FieldInfo{{Name: "ID", Type: "int64"}, {Name: "Title", Type: "string"}}}
+	structs := map[string]StructInfo{"Book": book}
+
+	methods := []MethodInfo{
+		{
+			Name:         "CreateBook",
+			Params:       []Param{{Name: "ctx", Type: "context.Context"}, {Name: "title", Type: "string"}},
+			Returns:      []Return{{Type: "Book"}, {Type: "error"}},
+			ReturnElem:   "Book",
+			ReturnsError: true,
+			HasValue:     true,
+			IsCreate:     true,
+		},
+		{
+			Name:         "GetBookByID",
+			Params:       []Param{{Name: "ctx", Type: "context.Context"}, {Name: "id", Type: "int64"}},
+			Returns:      []Return{{Type: "Book"}, {Type: "error"}},
+			ReturnElem:   "Book",
+			ReturnsError: true,
+			HasValue:     true,
+			IsSynthetic:  true,
+		},
+		{
+			Name: "UpdateBook",
+			Params: []Param{
+				{Name: "ctx", Type: "context.Context"},
+				{Name: "id", Type: "int64"},
+				{Name: "title", Type: "string"},
+			},
+			Returns:      []Return{{Type: "Book"}, {Type: "error"}},
+			ReturnElem:   "Book",
+			ReturnsError: true,
+			HasValue:     true,
+			IsUpdate:     true,
+		},
+	}
+
+	engData := PackageData{
+		Methods: []MethodInfo{
+			{Name: "CreateBook", Params: methods[0].Params, Returns: []Return{{Type: "sql.Result"}, {Type: "error"}}},
+			{Name: "UpdateBook", Params: methods[2].Params, Returns: []Return{{Type: "sql.Result"}, {Type: "error"}}},
+		},
+		Structs: structs,
+	}
+
+	tmpl := template.Must(template.New("wrapper").
+		Funcs(wrapperFuncMap(mysql, methods, structs, engData, nil)).
+		Parse(wrapperTemplate))
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, map[string]interface{}{
+		"Engine":      mysql,
+		"Methods":     methods,
+		"Structs":     structs,
+		"ImportBase":  "github.com/example/project/pkg/database",
+		"PackageName": "database",
+		"QueryErrors": true,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	output := string(formatFile("generated_wrapper_mysql.go", buf.Bytes()))
+
+	// GetBookByID wraps its own errors in a QueryError: the emulated RETURNING lookups must
+	// unwrap it, or CreateBook and UpdateBook would report "mysql: GetBookByID" inside theirs.
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.Contains(line, "GetBookByID(ctx, id)") && strings.HasPrefix(line, "return ") {
+			t.Errorf("expected the lookup error to be unwrapped, got %q\n%s", line, output)
+		}
+	}
+
+	for _, want := range []string{
+		"nf, err := w.GetBookByID(ctx, id)\n\terr = unwrapQueryError(err)",
+		"nf, err = (&mysqlWrapper{adapter: mysqldb.NewAdapter(w.adapter.DB())}).GetBookByID(ctx, id)\n\n\t" +
+			"return nf, unwrapQueryError(err)",
+		"updated, err := w.GetBookByID(ctx, id)\n\n\treturn updated, unwrapQueryError(err)",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected output to contain %q\n%s", want, output)
+		}
+	}
+}
+
+func TestWrapperTemplateRequireRows(t *testing.T) {
+	t.Parallel()
+
+	params := []Param{{Name: "ctx", Type: "context.Context"}, {Name: "id", Type: "int64"}}
+
+	// The unified method only returns an error, whatever the engine query returns.
+	methods := []MethodInfo{{
+		Name:         "DeleteUser",
+		Params:       params,
+		Returns:      []Return{{Type: "error"}},
+		ReturnsError: true,
+		RequireRows:  true,
+	}}
+
+	engineMethod := func(ret string) PackageData {
+		return PackageData{Methods: []MethodInfo{{
+			Name:    "DeleteUser",
+			Params:  params,
+			Returns: []Return{{Type: ret}, {Type: "error"}},
+		}}}
+	}
+
+	output := renderWrapper(t, Engine{Name: "sqlite", Package: "sqlitedb"}, methods, nil, engineMethod("int64"))
+
+	if !strings.Contains(output, "rows, err := w.adapter.DeleteUser(ctx, id)") ||
+		!strings.Contains(output, "return ErrNotFound") {
+		t.Errorf("expected the :execrows count to be checked\n%s", output)
+	}
+
+	output = renderWrapper(t, Engine{Name: "mysql", Package: "mysqldb"}, methods, nil, engineMethod("sql.Result"))
+
+	if !strings.Contains(output, "rows, err := res.RowsAffected()") || !strings.Contains(output, "return ErrNotFound") {
+		t.Errorf("expected the :execresult RowsAffected to be checked\n%s", output)
+	}
+
+	engines := []Engine{{Name: "postgres", Package: "postgresdb"}}
+	engineData := map[string]PackageData{
+		"postgres": {Methods: []MethodInfo{{
+			Name: "DeleteUser", Params: params, Returns: []Return{{Type: "error"}},
+		}}},
+	}
+
+	report := validationReport(methods, nil, engines, engineData)
+	if !strings.Contains(report, "@require-rows needs a :execrows (int64) or :execresult (sql.Result) query") {
+		t.Errorf("expected a :exec engine query to be reported, got %q", report)
+	}
+}
+
+func TestGenerateTx(t *testing.T) {
+	t.Parallel()
+
+	tx := string(generateTx("database"))
+
+	for _, want := range []string{
+		"func RunInTx(ctx context.Context, q Querier, opts *sql.TxOptions, fn func(Querier) error) error {",
+		"return runInSavepoint(ctx, tx, q, fn)",
+		"(!errors.Is(err, ErrDeadlock) && !errors.Is(err, ErrSerialization))",
+		"return classify(tx.Commit())",
+	} {
+		if !strings.Contains(tx, want) {
+			t.Errorf("expected RunInTx to contain %q\n%s", want, tx)
+		}
+	}
+
+	sqlite := renderWrapper(t, Engine{Name: "sqlite", Package: "sqlitedb"}, nil, nil, PackageData{})
+	if !strings.Contains(sqlite, "return &sql.TxOptions{ReadOnly: opts.ReadOnly}") {
+		t.Errorf("expected SQLite to drop the isolation level\n%s", sqlite)
+	}
+
+	postgres := renderWrapper(t, Engine{Name: "postgres", Package: "postgresdb"}, nil, nil, PackageData{})
+	if !strings.Contains(postgres, "case sql.LevelSnapshot:") ||
+		!strings.Contains(postgres, "return classifyPostgresError(err)") {
+		t.Errorf("expected PostgreSQL isolation mapping and commit error classification\n%s", postgres)
+	}
+}
+
+func TestWrapperTemplateWithDBTX(t *testing.T) {
+	t.Parallel()
+
+	output := renderWrapper(t, Engine{Name: "sqlite", Package: "sqlitedb"}, nil, nil, PackageData{})
+
+	for _, want := range []string{
+		"func (w *sqliteWrapper) WithDBTX(db DBTX) Querier {",
+		"return &sqliteWrapper{adapter: w.adapter.WithDBTX(db)}",
+		// A bulk call on a pinned *sql.Conn begins its transaction on that connection.
+		"tx, err := w.beginTx(ctx, nil)",
+		"return conn.BeginTx(ctx, opts)",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected output to contain %q\n%s", want, output)
+		}
+	}
+
+	// A hand-written Adapter from before WithDBTX existed is reported instead of leaving
+	// the generated wrapper to fail to compile.
+	dir := t.TempDir()
+	adapter := `package sqlitedb
+
+type Adapter struct{ *Queries }
+
+func NewAdapter(db *sql.DB) *Adapter { return nil }
+
+func (a *Adapter) DB() *sql.DB { return nil }
+
+func (a *Adapter) DBTX() DBTX { return nil }
+
+func (a *Adapter) WithTx(tx *sql.Tx) *Adapter { return nil }
+
+func (q *Queries) WithDBTX(db DBTX) *Queries { return nil }
+`
+	if err := os.WriteFile(filepath.Join(dir, "adapter.go"), []byte(adapter), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	engines := []Engine{{Name: "sqlite", Package: "sqlitedb"}}
+	engineData := map[string]PackageData{"sqlite": {AdapterMembers: adapterMembers(dir, adapterFileName)}}
+
+	report := validationReport(nil, nil, engines, engineData)
+
+	want := "Adapter: the hand-written Adapter lacks WithDBTX, which the wrapper calls"
+	if !strings.Contains(report, want) {
+		t.Errorf("expected report to contain %q, got:\n%s", want, report)
+	}
+
+	engineData["sqlite"].AdapterMembers["WithDBTX"] = true
+
+	if report := validationReport(nil, nil, engines, engineData); report != "" {
+		t.Errorf("expected a complete hand-written Adapter to be accepted, got:\n%s", report)
+	}
+}
+
+func TestWrapperTemplateBeginTx(t *testing.T) {
+	t.Parallel()
+
+	createUser := MethodInfo{
+		Name:         "CreateUser",
+		Params:       []Param{{Name: "ctx", Type: "context.Context"}, {Name: "name", Type: "string"}},
+		Returns:      []Return{{Type: "User"}, {Type: "error"}},
+		ReturnElem:   "User",
+		ReturnsError: true,
+		HasValue:     true,
+		IsCreate:     true,
+	}
+
+	engData := PackageData{
+		Methods: []MethodInfo{{
+			Name:    "CreateUser",
+			Params:  createUser.Params,
+			Returns: []Return{{Type: "sql.Result"}, {Type: "error"}},
+		}},
+	}
+
+	output := renderWrapper(t, Engine{Name: "mysql", Package: "mysqldb"},
+		[]MethodInfo{createUser}, nil, engData)
+
+	for _, want := range []string{
+		"func (w *mysqlWrapper) BeginTx(ctx context.Context, opts *sql.TxOptions) (TxQuerier, error) {",
+		// Every nested BeginTx gets its own savepoint.
+		`name := fmt.Sprintf("sqlc_multi_db_begin_%d", mysqlTxSavepoints.Add(1))`,
+		`tx.ExecContext(ctx, "SAVEPOINT "+name)`,
+		`t.tx.ExecContext(t.ctx, "ROLLBACK TO SAVEPOINT "+t.savepoint)`,
+		"return &mysqlTxWrapper{mysqlWrapper: &mysqlWrapper{adapter: w.adapter.WithTx(tx)}, ctx: ctx, tx: tx}, nil",
+		"func (t *mysqlTxWrapper) Commit() error {",
+		"func (t *mysqlTxWrapper) Rollback() error {",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected output to contain %q\n%s", want, output)
+		}
+	}
+
+	// The non-transactional GetByID fallback only runs inside a transaction.
+	guard := strings.Index(output, "if w.currentTx() == nil {")
+	fallback := strings.Index(output, "(&mysqlWrapper{adapter: mysqldb.NewAdapter(w.adapter.DB())})")
+
+	if guard == -1 || fallback == -1 || guard > fallback {
+		t.Errorf("expected the MySQL create fallback to be skipped outside transactions\n%s", output)
+	}
+}
+
+func TestGenerateRouting(t *testing.T) {
+	t.Parallel()
+
+	method := func(name string, edit func(*MethodInfo)) MethodInfo {
+		m := MethodInfo{
+			Name:         name,
+			Params:       []Param{{Name: "ctx", Type: "context.Context"}, {Name: "id", Type: "int64"}},
+			Returns:      []Return{{Type: "string"}, {Type: "error"}},
+			ReturnElem:   "string",
+			ReturnsError: true,
+			HasValue:     true,
+		}
+		edit(&m)
+
+		return m
+	}
+
+	methods := []MethodInfo{
+		method("GetUser", func(m *MethodInfo) { m.IsReadOnly = true }),
+		method("GetUserFresh", func(m *MethodInfo) { m.IsReadOnly, m.Primary = true, true }),
+		method("CountUsersFn", func(m *MethodInfo) { m.Replica = true }),
+		method("DeleteUser", func(m *MethodInfo) {
+			m.Returns, m.ReturnElem, m.HasValue = []Return{{Type: "error"}}, "", false
+		}),
+		method("GetUserSQLite", func(m *MethodInfo) { m.IsReadOnly, m.EngineOnly = true, []string{"sqlite"} }),
+	}
+
+	output := string(generateRouting("database", methods))
+
+	for _, want := range []string{
+		"func NewRoutingQuerier(primary, replica Querier) *RoutingQuerier {",
+		"return r.replica.GetUser(ctx, id)",
+		"return r.primary.GetUserFresh(ctx, id)",
+		"return r.replica.CountUsersFn(ctx, id)",
+		"return r.primary.DeleteUser(ctx, id)",
+		"func (r *RoutingQuerier) WithTx(tx *sql.Tx) Querier { return r.primary.WithTx(tx) }",
+		"return r.primary.BeginTx(ctx, opts)",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected RoutingQuerier to contain %q\n%s", want, output)
+		}
+	}
+
+	if strings.Contains(output, "GetUserSQLite") {
+		t.Errorf("expected engine-only methods to be left out of RoutingQuerier\n%s", output)
+	}
+
+	if tx := string(generateTx("database")); !strings.Contains(tx, "q = r.Primary()") {
+		t.Errorf("expected RunInTx to run a RoutingQuerier on its primary\n%s", tx)
+	}
+}
@@ -1,21 +1,12 @@
 package generator_test
 
 import (
-	"bytes"
-	"errors"
 	"go/ast"
-	"strings"
 	"testing"
-	"text/template"
 
 	"github.com/kalbasit/sqlc-multi-db/generator"
 )
 
-var (
-	errTestInvalidDictCall       = errors.New("invalid dict call")
-	errTestDictKeysMustBeStrings = errors.New("dict keys must be strings")
-)
-
 func TestExprToString(t *testing.T) {
 	t.Parallel()
 
@@ -163,332 +154,6 @@ func TestToSingular(t *testing.T) {
 	}
 }
 
-func TestJoinParamsCall(t *testing.T) {
-	t.Parallel()
-
-	tests := []struct {
-		name    string
-		params  []generator.Param
-		engPkg  string
-		want    string
-		wantErr bool
-	}{
-		{
-			name: "Simple Params",
-			params: []generator.Param{
-				{Name: "ctx", Type: "context.Context"},
-				{Name: "id", Type: "int64"},
-			},
-			engPkg: "sqlitedb",
-			want:   "ctx, id",
-		},
-		{
-			name: "Domain Struct Param",
-			params: []generator.Param{
-				{Name: "user", Type: "User"},
-			},
-			engPkg: "postgresdb",
-			want:   "postgresdb.User(user)",
-		},
-		{
-			name: "Unsupported Slice of Domain Struct",
-			params: []generator.Param{
-				{Name: "users", Type: "[]User"},
-			},
-			engPkg:  "postgresdb",
-			wantErr: true,
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			t.Parallel()
-
-			got, err := generator.JoinParamsCall(tt.params, tt.engPkg, generator.MethodInfo{}, nil, nil)
-			if (err != nil) != tt.wantErr {
-				t.Errorf("JoinParamsCall() error = %v, wantErr %v", err, tt.wantErr)
-
-				return
-			}
-
-			if got != tt.want {
-				t.Errorf("JoinParamsCall() = %v, want %v", got, tt.want)
-			}
-		})
-	}
-}
-
-func TestWrapperTemplate(t *testing.T) {
-	t.Parallel()
-
-	// Mock engines
-	sqlite := generator.Engine{Name: "sqlite", Package: "sqlitedb"}
-
-	// Mock structs
-	structs := map[string]generator.StructInfo{
-		"CreateUserParams": {
-			Name: "CreateUserParams",
-			Fields: []generator.FieldInfo{
-				{Name: "Username", Type: "string"},
-			},
-		},
-		"CreateUsersParams": {
-			Name: "CreateUsersParams",
-			Fields: []generator.FieldInfo{
-				{Name: "Usernames", Type: "[]string"},
-			},
-		},
-	}
-
-	// Mock methods
-	methods := []generator.MethodInfo{
-		{
-			Name: "CreateUsers",
-			Params: []generator.Param{
-				{Name: "ctx", Type: "context.Context"},
-				{Name: "arg", Type: "CreateUsersParams"},
-			},
-			Returns: []generator.Return{{Type: "error"}},
-			Docs:    []string{"// CreateUsers creates users"},
-		},
-	}
-
-	funcMap := template.FuncMap{
-		"hasParam":            generator.HasParam,
-		"paramHasField":       generator.ParamHasField,
-		"joinParamsSignature": generator.JoinParamsSignature,
-		"joinReturns":         generator.JoinReturns,
-		"isSlice":             generator.IsSlice,
-		"firstReturnType":     generator.FirstReturnType,
-		"isDomainStruct":      generator.IsDomainStructFunc,
-		"zeroValue":           generator.ZeroValue,
-		"getStruct":           func(name string) generator.StructInfo { return structs[name] },
-		"hasSliceField":       generator.HasSliceField,
-		"getSliceField":       generator.GetSliceField,
-		"toSingular":          generator.ToSingular,
-		"trimPrefix":          strings.TrimPrefix,
-		"dict": func(values ...interface{}) (map[string]interface{}, error) {
-			if len(values)%2 != 0 {
-				return nil, errTestInvalidDictCall
-			}
-
-			dict := make(map[string]interface{}, len(values)/2)
-			for i := 0; i < len(values); i += 2 {
-				key, ok := values[i].(string)
-				if !ok {
-					return nil, errTestDictKeysMustBeStrings
-				}
-
-				dict[key] = values[i+1]
-			}
-
-			return dict, nil
-		},
-		"getTargetMethod": func(name string) generator.MethodInfo {
-			if name == "CreateUsers" {
-				return generator.MethodInfo{
-					Name: "CreateUsers",
-					Params: []generator.Param{
-						{Name: "ctx", Type: "context.Context"},
-						{Name: "arg", Type: "CreateUsersParams"},
-					},
-					Returns: []generator.Return{{Type: "error"}},
-				}
-			}
-
-			return generator.MethodInfo{}
-		},
-		"getTargetStruct": func(name string) generator.StructInfo { return structs[name] },
-		"joinParamsCall": func(params []generator.Param, engPkg string, targetMethodName string) (string, error) {
-			targetMethod := generator.MethodInfo{}
-			if targetMethodName == "CreateUsers" {
-				targetMethod = generator.MethodInfo{
-					Name: "CreateUsers",
-					Params: []generator.Param{
-						{Name: "ctx", Type: "context.Context"},
-						{Name: "arg", Type: "CreateUsersParams"},
-					},
-					Returns: []generator.Return{{Type: "error"}},
-				}
-			}
-
-			return generator.JoinParamsCall(params, engPkg, targetMethod, structs, structs)
-		},
-		"hasSuffix":               strings.HasSuffix,
-		"toSnakeCase":             generator.ToSnakeCase,
-		"quote":                   generator.Quote,
-		"generateFieldConversion": generator.GenerateFieldConversion,
-		"zeroReturn": func(m generator.MethodInfo) string {
-			if m.ReturnsSelf {
-				return "nil"
-			}
-
-			return "0"
-		},
-		"getTableName": func(_ string) string { return "users" },
-	}
-
-	tmpl, err := template.New("wrapper").Funcs(funcMap).Parse(generator.WrapperTemplate)
-	if err != nil {
-		t.Fatalf("failed to parse template: %v", err)
-	}
-
-	data := map[string]interface{}{
-		"Engine":      sqlite,
-		"Methods":     methods,
-		"Structs":     structs,
-		"ImportBase":  "github.com/example/project/pkg/database",
-		"PackageName": "database",
-	}
-
-	var buf bytes.Buffer
-	if err := tmpl.Execute(&buf, data); err != nil {
-		t.Fatalf("failed to execute template: %v", err)
-	}
-
-	output := buf.String()
-
-	// Verify auto-looping was triggered
-	if !strings.Contains(output, "for i, v := range arg.Usernames") {
-		t.Errorf("expected output to contain loop over arg.Usernames, but it didn't\n%s", output)
-	}
-
-	// Verify field mapping by type
-	if !strings.Contains(output, "Username: v,") {
-		t.Errorf("expected output to contain 'Username: v,', but it didn't\n%s", output)
-	}
-
-	// 2. Test GetStatus (should NOT loop because GetStatuParams does not exist)
-	methods = []generator.MethodInfo{
-		{
-			Name: "GetStatus",
-			Params: []generator.Param{
-				{Name: "ctx", Type: "context.Context"},
-				{Name: "hash", Type: "string"},
-			},
-			Returns: []generator.Return{{Type: "Status"}, {Type: "error"}},
-			Docs:    []string{"// GetStatus gets status"},
-		},
-	}
-
-	data["Methods"] = methods
-
-	buf.Reset()
-
-	if err := tmpl.Execute(&buf, data); err != nil {
-		t.Fatalf("failed to execute template: %v", err)
-	}
-
-	output = buf.String()
-	if strings.Contains(output, "for _, v := range") {
-		t.Errorf("expected output NOT to contain loop for GetStatus, but it did\n%s", output)
-	}
-
-	// 3. Test ReturnsSelf (WithTx)
-	methods = []generator.MethodInfo{
-		{
-			Name:         "WithTx",
-			Params:       []generator.Param{{Name: "tx", Type: "*sql.Tx"}},
-			Returns:      []generator.Return{{Type: "Querier"}, {Type: "error"}},
-			ReturnsSelf:  true,
-			ReturnsError: true,
-			HasValue:     true,
-			Docs:         []string{"// WithTx returns a new Querier with transaction"},
-		},
-	}
-
-	data["Methods"] = methods
-
-	buf.Reset()
-
-	if err := tmpl.Execute(&buf, data); err != nil {
-		t.Fatalf("failed to execute template: %v", err)
-	}
-
-	output = buf.String()
-	if !strings.Contains(output, "nil, ErrNotFound") {
-		t.Errorf("expected output to contain 'nil, ErrNotFound' for WithTx, but it didn't\n%s", output)
-	}
-
-	if !strings.Contains(output, "nil, err") {
-		t.Errorf("expected output to contain 'nil, err' for WithTx, but it didn't\n%s", output)
-	}
-
-	// 4. Test sql.NullString conversion
-	methods = []generator.MethodInfo{
-		{
-			Name: "CreateUser",
-			Params: []generator.Param{
-				{Name: "ctx", Type: "context.Context"},
-				{Name: "user", Type: "User"},
-			},
-			Returns: []generator.Return{{Type: "error"}},
-		},
-	}
-
-	structs["CreateUserParams"] = generator.StructInfo{
-		Name: "CreateUserParams",
-		Fields: []generator.FieldInfo{
-			{Name: "Bio", Type: "sql.NullString"},
-		},
-	}
-	structs["User"] = generator.StructInfo{
-		Name: "User",
-		Fields: []generator.FieldInfo{
-			{Name: "Bio", Type: "string"},
-		},
-	}
-
-	funcMap["getTargetMethod"] = func(name string) generator.MethodInfo {
-		if name == "CreateUser" {
-			return generator.MethodInfo{
-				Name: "CreateUser",
-				Params: []generator.Param{
-					{Name: "ctx", Type: "context.Context"},
-					{Name: "arg", Type: "CreateUserParams"},
-				},
-				Returns: []generator.Return{{Type: "error"}},
-			}
-		}
-
-		return generator.MethodInfo{}
-	}
-
-	funcMap["joinParamsCall"] = func(params []generator.Param, engPkg string, _ string) (string, error) {
-		targetMethod := generator.MethodInfo{
-			Name: "CreateUser",
-			Params: []generator.Param{
-				{Name: "ctx", Type: "context.Context"},
-				{Name: "arg", Type: "CreateUserParams"},
-			},
-			Returns: []generator.Return{{Type: "error"}},
-		}
-
-		return generator.JoinParamsCall(params, engPkg, targetMethod, structs, structs)
-	}
-	funcMap["getTableName"] = func(_ string) string { return "users" }
-
-	tmpl, err = template.New("wrapper").Funcs(funcMap).Parse(generator.WrapperTemplate)
-	if err != nil {
-		t.Fatalf("failed to parse template: %v", err)
-	}
-
-	data["Methods"] = methods
-
-	buf.Reset()
-
-	if err := tmpl.Execute(&buf, data); err != nil {
-		t.Fatalf("failed to execute template: %v", err)
-	}
-
-	output = buf.String()
-
-	expectedConversion := "Bio: sql.NullString{String: user.Bio, Valid: true}"
-	if !strings.Contains(output, expectedConversion) {
-		t.Errorf("expected output to contain '%s', but it didn't\n%s", expectedConversion, output)
-	}
-}
-
 func TestGenerateFieldConversion(t *testing.T) {
 	t.Parallel()
 
@@ -554,79 +219,22 @@ func TestGenerateFieldConversion(t *testing.T) {
 	}
 }
 
-// TestJoinParamsCallFieldMapping tests that JoinParamsCall correctly maps
-// struct fields even when field names differ between source and target.
-// This is a regression test for the MySQL LIMIT parameter issue where
-// sqlc generates different field names (e.g., BatchSize vs Limit).
-func TestJoinParamsCallFieldMapping(t *testing.T) {
+func TestEngineExportedName(t *testing.T) {
 	t.Parallel()
 
-	// Source structs (domain) - what the wrapper API uses
-	sourceStructs := map[string]generator.StructInfo{
-		"GetStuckNarFilesParams": {
-			Name: "GetStuckNarFilesParams",
-			Fields: []generator.FieldInfo{
-				{Name: "CutoffTime", Type: "time.Time"},
-				{Name: "BatchSize", Type: "int32"},
-			},
-		},
-	}
-
-	// Target structs (adapter) - what the database engine generates
-	// MySQL generates different names: CreatedAt instead of CutoffTime, Limit instead of BatchSize
-	targetStructs := map[string]generator.StructInfo{
-		"GetStuckNarFilesParams": {
-			Name: "GetStuckNarFilesParams",
-			Fields: []generator.FieldInfo{
-				{Name: "CreatedAt", Type: "time.Time"},
-				{Name: "Limit", Type: "int32"},
-			},
-		},
-	}
-
-	// Target method info
-	targetMethod := generator.MethodInfo{
-		Name: "GetStuckNarFiles",
-		Params: []generator.Param{
-			{Name: "ctx", Type: "context.Context"},
-			{Name: "arg", Type: "GetStuckNarFilesParams"},
-		},
-	}
-
 	tests := []struct {
-		name    string
-		params  []generator.Param
-		engPkg  string
-		want    string
-		wantErr bool
+		name string
+		want string
 	}{
-		{
-			name: "Field mapping with different names",
-			params: []generator.Param{
-				{Name: "ctx", Type: "context.Context"},
-				{Name: "arg", Type: "GetStuckNarFilesParams"},
-			},
-			engPkg: "mysqldb",
-			// Expected: both fields should be mapped even though names differ
-			// The target struct uses its own field names (CreatedAt, Limit), not source names
-			want: "ctx, mysqldb.GetStuckNarFilesParams{\nCreatedAt: arg.CutoffTime,\nLimit: arg.BatchSize,\n}",
-		},
+		{"sqlite", "SQLite"},
+		{"postgres", "Postgres"},
+		{"mysql", "MySQL"},
+		{"cockroach", "Cockroach"},
 	}
 
 	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			t.Parallel()
-
-			got, err := generator.JoinParamsCall(tt.params, tt.engPkg, targetMethod, targetStructs, sourceStructs)
-			if (err != nil) != tt.wantErr {
-				t.Errorf("JoinParamsCall() error = %v, wantErr %v", err, tt.wantErr)
-
-				return
-			}
-
-			if got != tt.want {
-				t.Errorf("JoinParamsCall() = %v, want %v", got, tt.want)
-			}
-		})
+		if got := (generator.Engine{Name: tt.name}).ExportedName(); got != tt.want {
+			t.Errorf("ExportedName(%q) = %q, want %q", tt.name, got, tt.want)
+		}
 	}
 }
@@ -2,6 +2,10 @@ package generator
 
 import (
 	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
 	"log"
 	"os"
 	"path/filepath"
@@ -39,10 +43,12 @@ func quote(e Engine, s string) string {
 	return `\"` + s + `\"`
 }
 
-func extractBulkFor(comment string) string {
+// extractAnnotation returns the value following the first occurrence of the annotation
+// (e.g. "@bulk-for") in a comment, or an empty string if it is absent or has no value.
+func extractAnnotation(comment, annotation string) string {
 	parts := strings.Fields(comment)
 	for i, p := range parts {
-		if p == "@bulk-for" && i+1 < len(parts) {
+		if p == annotation && i+1 < len(parts) {
 			return parts[i+1]
 		}
 	}
@@ -50,9 +56,129 @@ func extractBulkFor(comment string) string {
 	return ""
 }
 
+// hasAnnotation reports whether a comment contains a flag annotation such as "@require-rows".
+func hasAnnotation(comment, annotation string) bool {
+	for _, p := range strings.Fields(comment) {
+		if p == annotation {
+			return true
+		}
+	}
+
+	return false
+}
+
+func extractBulkFor(comment string) string { return extractAnnotation(comment, "@bulk-for") }
+
+// extractEngineList returns the comma-separated engine names following an annotation
+// such as "@engines postgres,mysql".
+func extractEngineList(comment, annotation string) []string {
+	value := extractAnnotation(comment, annotation)
+	if value == "" {
+		return nil
+	}
+
+	var engines []string
+
+	for _, name := range strings.Split(value, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			engines = append(engines, name)
+		}
+	}
+
+	return engines
+}
+
+// readOnlyCalls are the words that may be followed by a parenthesis in a read-only query:
+// SQL keywords and functions without side effects. Any other call, such as nextval() or
+// pg_advisory_lock(), may write.
+var readOnlyCalls = map[string]bool{
+	// Keywords
+	"ALL": true, "AND": true, "ANY": true, "ARRAY": true, "AS": true, "BETWEEN": true, "BY": true,
+	"CASE": true, "CAST": true, "DISTINCT": true, "ELSE": true, "EXCEPT": true, "EXISTS": true,
+	"FILTER": true, "FROM": true, "IN": true, "INTERSECT": true, "IS": true, "JOIN": true,
+	"LATERAL": true, "LIKE": true, "ILIKE": true, "LIMIT": true, "NOT": true, "OFFSET": true,
+	"ON": true, "OR": true, "OVER": true, "ROW": true, "SELECT": true, "SOME": true,
+	"THEN": true, "UNION": true, "USING": true, "VALUES": true, "WHEN": true, "WHERE": true,
+	"WITH": true, "WITHIN": true,
+	// Aggregate and window functions
+	"ARRAY_AGG": true, "AVG": true, "BOOL_AND": true, "BOOL_OR": true, "COUNT": true,
+	"DENSE_RANK": true, "GROUP_CONCAT": true, "JSON_AGG": true, "JSON_ARRAYAGG": true,
+	"JSON_GROUP_ARRAY": true, "JSONB_AGG": true, "LAG": true, "LEAD": true, "MAX": true, "MIN": true,
+	"RANK": true, "ROW_NUMBER": true, "STRING_AGG": true, "SUM": true,
+	// Scalar functions
+	"ABS": true, "CEIL": true, "CEILING": true, "CHAR_LENGTH": true, "COALESCE": true, "CONCAT": true,
+	"CURRENT_DATE": true, "CURRENT_TIMESTAMP": true, "DATE": true, "DATE_TRUNC": true, "DATETIME": true,
+	"EXTRACT": true, "FLOOR": true, "GREATEST": true, "IF": true, "IFNULL": true, "JSON_ARRAY_LENGTH": true,
+	"JSON_EACH": true, "JSON_EXTRACT": true, "LEAST": true, "LENGTH": true, "LOWER": true, "LTRIM": true,
+	"NOW": true, "NULLIF": true, "PLAINTO_TSQUERY": true, "ROUND": true, "RTRIM": true, "STRFTIME": true,
+	"SUBSTR": true, "SUBSTRING": true, "TO_TSQUERY": true, "TO_TSVECTOR": true, "TRIM": true,
+	"TS_RANK": true, "UNNEST": true, "UPPER": true,
+}
+
+// isReadOnlyQuery reports whether a query only reads: a SELECT, or a WITH query whose
+// statements are all SELECTs, without locking clauses such as FOR UPDATE, FOR SHARE or
+// LOCK IN SHARE MODE, and without calls to functions that may write.
+func isReadOnlyQuery(query string) bool {
+	readOnly, _ := readOnlyQuery(query)
+
+	return readOnly
+}
+
+// readOnlyQuery is isReadOnlyQuery, also returning the function call that makes an
+// otherwise read-only query count as a write.
+func readOnlyQuery(query string) (bool, string) {
+	query = strings.ToUpper(query)
+
+	isWord := func(c byte) bool { return (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || c == '_' }
+
+	var (
+		words []string
+		call  string
+	)
+
+	for i := 0; i < len(query); {
+		if !isWord(query[i]) {
+			i++
+
+			continue
+		}
+
+		start := i
+		for i < len(query) && isWord(query[i]) {
+			i++
+		}
+
+		word := query[start:i]
+		words = append(words, word)
+
+		next := strings.TrimLeft(query[i:], " \t\r\n")
+		if call == "" && strings.HasPrefix(next, "(") && !readOnlyCalls[word] {
+			call = strings.ToLower(word)
+		}
+	}
+
+	if len(words) == 0 || (words[0] != "SELECT" && words[0] != "WITH") {
+		return false, ""
+	}
+
+	for i, w := range words {
+		switch w {
+		case "INSERT", "UPDATE", "DELETE", "REPLACE", "RETURNING":
+			return false, ""
+		case "SHARE":
+			if i > 0 && (words[i-1] == "FOR" || words[i-1] == "KEY" || words[i-1] == "IN") {
+				return false, ""
+			}
+		}
+	}
+
+	return call == "", call
+}
+
 func toSingular(s string) string { return inflection.Singular(s) }
 
-func writeFile(dir, filename string, content []byte) {
+// formatFile runs goimports and gofumpt on a rendered file.
+func formatFile(filename string, content []byte) []byte {
 	// 1. Manage imports with goimports
 	withImports, err := imports.Process(filename, content, nil)
 	if err != nil {
@@ -70,13 +196,114 @@ func writeFile(dir, filename string, content []byte) {
 		log.Fatalf("formatting %s: %v", filename, err)
 	}
 
-	if err := os.WriteFile(filepath.Join(dir, filename), formatted, 0o644); err != nil { //nolint:gosec
+	return formatted
+}
+
+// writeFile writes a formatted file to dir.
+func writeFile(dir, filename string, content []byte) {
+	if err := os.WriteFile(filepath.Join(dir, filename), content, 0o644); err != nil { //nolint:gosec
 		log.Fatal(err)
 	}
 
 	fmt.Printf("Generated %s\n", filename)
 }
 
+// declaresIdent reports whether a Go file in dir, other than skipFile, declares a
+// top-level type, function, variable or constant named name.
+func declaresIdent(dir, name, skipFile string) bool {
+	fset := token.NewFileSet()
+
+	pkgs, err := parser.ParseDir(fset, dir, func(fi fs.FileInfo) bool {
+		return fi.Name() != skipFile && !strings.HasSuffix(fi.Name(), "_test.go")
+	}, 0)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	for _, pkg := range pkgs {
+		for _, file := range pkg.Files {
+			for _, decl := range file.Decls {
+				if declNames(decl)[name] {
+					return true
+				}
+			}
+		}
+	}
+
+	return false
+}
+
+// adapterMembers returns NewAdapter and the methods declared on Adapter by the Go files in
+// dir other than skipFile. Methods promoted from the embedded *Queries are left out, as the
+// wrapper needs the ones returning *Adapter.
+func adapterMembers(dir, skipFile string) map[string]bool {
+	fset := token.NewFileSet()
+
+	pkgs, err := parser.ParseDir(fset, dir, func(fi fs.FileInfo) bool {
+		return fi.Name() != skipFile && !strings.HasSuffix(fi.Name(), "_test.go")
+	}, 0)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	members := make(map[string]bool)
+
+	for _, pkg := range pkgs {
+		for _, file := range pkg.Files {
+			for _, decl := range file.Decls {
+				fn, ok := decl.(*ast.FuncDecl)
+				if !ok {
+					continue
+				}
+
+				if fn.Recv == nil {
+					if fn.Name.Name == "NewAdapter" {
+						members[fn.Name.Name] = true
+					}
+
+					continue
+				}
+
+				recv := fn.Recv.List[0].Type
+				if star, ok := recv.(*ast.StarExpr); ok {
+					recv = star.X
+				}
+
+				if ident, ok := recv.(*ast.Ident); ok && ident.Name == "Adapter" {
+					members[fn.Name.Name] = true
+				}
+			}
+		}
+	}
+
+	return members
+}
+
+// declNames returns the top-level identifiers declared by decl. Methods are not included.
+func declNames(decl ast.Decl) map[string]bool {
+	names := make(map[string]bool)
+
+	switch d := decl.(type) {
+	case *ast.FuncDecl:
+		if d.Recv == nil {
+			names[d.Name.Name] = true
+		}
+	case *ast.GenDecl:
+		for _, spec := range d.Specs {
+			switch s := spec.(type) {
+			case *ast.TypeSpec:
+				names[s.Name.Name] = true
+			case *ast.ValueSpec:
+				for _, n := range s.Names {
+					names[n.Name] = true
+				}
+			}
+		}
+	}
+
+	return names
+}
+
 // hasParam checks if a parameter with the given name exists in the params list.
 func hasParam(name string, params []Param) bool {
 	for _, param := range params {
@@ -123,39 +350,40 @@ func joinParamsSignature(params []Param) string {
 	return strings.Join(p, ", ")
 }
 
-// JoinParamsCall is exported for use in tests.
-func JoinParamsCall(
-	params []Param,
-	engPkg string,
-	targetMethod MethodInfo,
-	targetStructs map[string]StructInfo,
-	sourceStructs map[string]StructInfo,
-) (string, error) {
-	return joinParamsCall(params, engPkg, targetMethod, targetStructs, sourceStructs)
-}
+// matchStrategy records how findSourceField paired a target field with a source field.
+type matchStrategy int
+
+const (
+	matchNone matchStrategy = iota
+	matchExact
+	matchCaseInsensitive
+	matchSnakeCase
+	matchSingular
+	matchPosition
+)
 
 // findSourceField finds a matching field in available source fields using multiple strategies:
 // 1. Exact name match
 // 2. Case-insensitive match
 // 3. Snake_case match
 // 4. Position-based match (fallback when structs have same field count).
-// The availableSourceFields map is modified to remove matched fields.
+// The returned strategy is matchNone when no field matched.
 func findSourceField(
 	targetField FieldInfo,
 	targetIdx int,
 	targetStruct StructInfo,
 	sourceStruct StructInfo,
 	availableSourceFields map[string]FieldInfo,
-) (FieldInfo, bool) {
+) (FieldInfo, matchStrategy) {
 	// Strategy 1: Exact name match
 	if sf, ok := availableSourceFields[targetField.Name]; ok {
-		return sf, true
+		return sf, matchExact
 	}
 
 	// Strategy 2: Case-insensitive match
 	for _, sf := range availableSourceFields {
 		if strings.EqualFold(sf.Name, targetField.Name) {
-			return sf, true
+			return sf, matchCaseInsensitive
 		}
 	}
 
@@ -163,32 +391,165 @@ func findSourceField(
 	targetSnake := toSnakeCase(targetField.Name)
 	for _, sf := range availableSourceFields {
 		if toSnakeCase(sf.Name) == targetSnake {
-			return sf, true
+			return sf, matchSnakeCase
 		}
 	}
 
 	// Strategy 4: Position-based match (fallback when structs have same field count)
 	// Only use position matching if the structs have the same number of fields
 	if len(sourceStruct.Fields) != len(targetStruct.Fields) || len(sourceStruct.Fields) == 0 {
-		return FieldInfo{}, false
+		return FieldInfo{}, matchNone
 	}
 	// Match by position - use the field at the same index in source
 	if targetIdx >= len(sourceStruct.Fields) {
-		return FieldInfo{}, false
+		return FieldInfo{}, matchNone
 	}
 
 	originalSourceField := sourceStruct.Fields[targetIdx]
 	// Check if it's still available
 	sf, ok := availableSourceFields[originalSourceField.Name]
 	if !ok {
-		return FieldInfo{}, false
+		return FieldInfo{}, matchNone
 	}
 	// Verify types are compatible
 	if fieldsCompatible(sf.Type, targetField.Type) {
-		return sf, true
+		return sf, matchPosition
+	}
+
+	return FieldInfo{}, matchNone
+}
+
+// fieldDiagnostic returns the diagnostic for a target field matched with strategy, if any.
+// Only dropped fields and positional matches are worth reporting.
+func fieldDiagnostic(
+	targetStruct StructInfo, targetField, sourceField FieldInfo, strategy matchStrategy,
+) (Diagnostic, bool) {
+	switch strategy {
+	case matchNone:
+		return Diagnostic{
+			Struct: targetStruct.Name,
+			Field:  targetField.Name,
+			Reason: "no matching source field, left zero-valued",
+		}, true
+	case matchPosition:
+		return Diagnostic{
+			Struct: targetStruct.Name,
+			Field:  targetField.Name,
+			Reason: "matched by position to source field " + sourceField.Name,
+		}, true
+	case matchExact, matchCaseInsensitive, matchSnakeCase, matchSingular:
+	}
+
+	return Diagnostic{}, false
+}
+
+// convertStructFields builds the keyed fields of a struct literal converting sourceStruct,
+// read through sourcePrefix (e.g. "arg."), into targetStruct. The two structs do not need
+// to share a name: fields are paired with findSourceField, and target fields without a
+// matching source field are left out of the literal and reported as diagnostics.
+//
+// Fields are only paired by position when positional is set. Result conversions leave it
+// unset: a renamed or reordered column would otherwise silently fill a field with the
+// value of another column of the same type.
+func convertStructFields(
+	targetStruct, sourceStruct StructInfo, sourcePrefix string, positional bool,
+) ([]string, []Diagnostic) {
+	// Create a map of available source fields to track which fields have been mapped.
+	availableSourceFields := make(map[string]FieldInfo, len(sourceStruct.Fields))
+	for _, sf := range sourceStruct.Fields {
+		availableSourceFields[sf.Name] = sf
+	}
+
+	fields := make([]string, 0, len(targetStruct.Fields))
+
+	var diags []Diagnostic
+
+	for targetIdx, targetField := range targetStruct.Fields {
+		sourceField, strategy := findSourceField(targetField, targetIdx, targetStruct, sourceStruct, availableSourceFields)
+		if strategy == matchPosition && !positional {
+			strategy = matchNone
+		}
+
+		if d, ok := fieldDiagnostic(targetStruct, targetField, sourceField, strategy); ok {
+			diags = append(diags, d)
+		}
+
+		if strategy == matchNone {
+			continue
+		}
+
+		fields = append(fields, generateFieldConversion(
+			targetField.Name,
+			targetField.Type,
+			sourceField.Type,
+			sourcePrefix+sourceField.Name,
+		))
+		// Remove the mapped field so it can't be used again.
+		delete(availableSourceFields, sourceField.Name)
 	}
 
-	return FieldInfo{}, false
+	return fields, diags
+}
+
+// findBulkField finds the bulk struct field feeding targetField of the singular Params
+// struct: the field with the same name, then the field whose singular form matches
+// case-insensitively (e.g. "BookIds" for "BookID"), and finally the field at the same position.
+func findBulkField(targetField FieldInfo, targetIdx int, bulkStruct StructInfo) (FieldInfo, matchStrategy) {
+	for _, bf := range bulkStruct.Fields {
+		if bf.Name == targetField.Name {
+			return bf, matchExact
+		}
+	}
+
+	for _, bf := range bulkStruct.Fields {
+		if strings.EqualFold(toSingular(bf.Name), targetField.Name) {
+			return bf, matchSingular
+		}
+	}
+
+	if targetIdx < len(bulkStruct.Fields) {
+		return bulkStruct.Fields[targetIdx], matchPosition
+	}
+
+	return FieldInfo{}, matchNone
+}
+
+// bulkLoopFields builds the keyed fields of the singular Params literal for element i of a
+// bulk auto-loop ranging over sliceField, where v is the current element. Slice fields of the
+// bulk struct are indexed with i and scalar fields are passed through as-is.
+func bulkLoopFields(
+	targetStruct, bulkStruct StructInfo, sliceField FieldInfo, argName string,
+) ([]string, []Diagnostic) {
+	fields := make([]string, 0, len(targetStruct.Fields))
+
+	var diags []Diagnostic
+
+	for targetIdx, targetField := range targetStruct.Fields {
+		sourceField, strategy := findBulkField(targetField, targetIdx, bulkStruct)
+		if d, ok := fieldDiagnostic(targetStruct, targetField, sourceField, strategy); ok {
+			diags = append(diags, d)
+		}
+
+		if strategy == matchNone {
+			continue
+		}
+
+		srcExpr := argName + "." + sourceField.Name
+		srcType := sourceField.Type
+
+		switch {
+		case sourceField.Name == sliceField.Name:
+			srcExpr = "v"
+			srcType = strings.TrimPrefix(srcType, "[]")
+		case isSlice(sourceField.Type) && sourceField.Type != typeBytes:
+			srcExpr += "[i]"
+			srcType = strings.TrimPrefix(srcType, "[]")
+		}
+
+		fields = append(fields, generateFieldConversion(targetField.Name, targetField.Type, srcType, srcExpr))
+	}
+
+	return fields, diags
 }
 
 // fieldsCompatible checks if two field types are compatible for mapping.
@@ -234,6 +595,19 @@ func normalizeType(t string) string {
 	return t
 }
 
+// lookupTargetStruct returns the engine struct named typeName. Target struct keys may
+// include the package prefix (e.g., "mysqldb.GetStuckNarFilesParams"), so the prefixed
+// key is tried first, then the bare name.
+func lookupTargetStruct(targetStructs map[string]StructInfo, engPkg, typeName string) StructInfo {
+	if engPkg != "" {
+		if s, ok := targetStructs[engPkg+"."+typeName]; ok {
+			return s
+		}
+	}
+
+	return targetStructs[typeName]
+}
+
 func joinDomainStructParam(
 	param Param,
 	i int,
@@ -241,9 +615,9 @@ func joinDomainStructParam(
 	targetMethod MethodInfo,
 	targetStructs map[string]StructInfo,
 	sourceStructs map[string]StructInfo,
-) (string, error) {
+) (string, []Diagnostic, error) {
 	if strings.HasPrefix(param.Type, "[]") {
-		return "", errUnsupportedSliceDomainStruct(param.Type)
+		return "", nil, errUnsupportedSliceDomainStruct(param.Type)
 	}
 
 	targetParamType := ""
@@ -253,46 +627,13 @@ func joinDomainStructParam(
 
 	if targetParamType != "" {
 		sourceStruct := sourceStructs[param.Type]
-		// Target struct keys may include the package prefix (e.g., "mysqldb.GetStuckNarFilesParams")
-		// Try with prefix first, then without
-		targetStructKey := targetParamType
-		if engPkg != "" {
-			if _, ok := targetStructs[engPkg+"."+targetParamType]; ok {
-				targetStructKey = engPkg + "." + targetParamType
-			}
-			// Otherwise keep using targetParamType (no prefix)
-		}
-
-		targetStruct := targetStructs[targetStructKey]
-
-		// Create a map of available source fields to track which fields have been mapped.
-		availableSourceFields := make(map[string]FieldInfo, len(sourceStruct.Fields))
-		for _, sf := range sourceStruct.Fields {
-			availableSourceFields[sf.Name] = sf
-		}
-
-		var fields []string
+		targetStruct := lookupTargetStruct(targetStructs, engPkg, targetParamType)
+		fields, diags := convertStructFields(targetStruct, sourceStruct, param.Name+".", true)
 
-		for targetIdx, targetField := range targetStruct.Fields {
-			sourceField, found := findSourceField(targetField, targetIdx, targetStruct, sourceStruct, availableSourceFields)
-
-			if found {
-				conversion := generateFieldConversion(
-					targetField.Name,
-					targetField.Type,
-					sourceField.Type,
-					fmt.Sprintf("%s.%s", param.Name, sourceField.Name),
-				)
-				fields = append(fields, conversion)
-				// Remove the mapped field so it can't be used again.
-				delete(availableSourceFields, sourceField.Name)
-			}
-		}
-
-		return fmt.Sprintf("%s.%s{\n%s,\n}", engPkg, targetParamType, strings.Join(fields, ",\n")), nil
+		return fmt.Sprintf("%s.%s{\n%s,\n}", engPkg, targetParamType, strings.Join(fields, ",\n")), diags, nil
 	}
 
-	return fmt.Sprintf("%s.%s(%s)", engPkg, param.Type, param.Name), nil
+	return fmt.Sprintf("%s.%s(%s)", engPkg, param.Type, param.Name), nil, nil
 }
 
 func joinNonDomainParam(param Param, i int, targetMethod MethodInfo) string {
@@ -308,29 +649,189 @@ func joinNonDomainParam(param Param, i int, targetMethod MethodInfo) string {
 	return param.Name
 }
 
+// paramsShapeMatches reports whether params can be paired with the parameters of
+// targetMethod by index: both sides have the same number of parameters and struct
+// parameters line up with struct parameters. An unknown target method always matches.
+func paramsShapeMatches(params []Param, targetMethod MethodInfo) bool {
+	if len(targetMethod.Params) == 0 {
+		return true
+	}
+
+	if len(params) != len(targetMethod.Params) {
+		return false
+	}
+
+	for i, param := range params {
+		if isDomainStructFunc(param.Type) != isDomainStructFunc(targetMethod.Params[i].Type) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// trimDuplicateSuffix removes the numeric suffix sqlc appends to repeated parameter
+// names (e.g. "ID_2" becomes "ID").
+func trimDuplicateSuffix(name string) string {
+	idx := strings.LastIndex(name, "_")
+	if idx <= 0 || idx == len(name)-1 {
+		return name
+	}
+
+	for _, r := range name[idx+1:] {
+		if !unicode.IsDigit(r) {
+			return name
+		}
+	}
+
+	return name[:idx]
+}
+
+// findParamValue finds the source value feeding targetField among values, using the
+// findSourceField strategies followed by a lookup without sqlc's duplicate suffix.
+// Values are not consumed: the same scalar may feed several fields, which is how sqlc
+// models a parameter repeated in the query.
+func findParamValue(targetField FieldInfo, targetIdx int, targetStruct, values StructInfo) (FieldInfo, matchStrategy) {
+	available := make(map[string]FieldInfo, len(values.Fields))
+	for _, v := range values.Fields {
+		available[v.Name] = v
+	}
+
+	if sf, strategy := findSourceField(targetField, targetIdx, targetStruct, values, available); strategy != matchNone {
+		return sf, strategy
+	}
+
+	if base := trimDuplicateSuffix(targetField.Name); base != targetField.Name {
+		return findSourceField(FieldInfo{Name: base, Type: targetField.Type}, targetIdx, targetStruct, values, available)
+	}
+
+	return FieldInfo{}, matchNone
+}
+
+// convertValue returns the expression converting sourceExpr of sourceType into targetType.
+func convertValue(targetType, sourceType, sourceExpr string) string {
+	const name = "v"
+
+	return strings.TrimPrefix(generateFieldConversion(name, targetType, sourceType, sourceExpr), name+": ")
+}
+
+// joinReshapedParams builds the call arguments when the source and target methods group
+// their parameters differently, e.g. scalars on one engine and a Params struct on another.
+// Every source value (scalar params and the fields of struct params) is made available by
+// name, and each target parameter or target struct field picks its value from them.
+func joinReshapedParams(
+	params []Param,
+	engPkg string,
+	targetMethod MethodInfo,
+	targetStructs map[string]StructInfo,
+	sourceStructs map[string]StructInfo,
+) (string, []Diagnostic, error) {
+	values := StructInfo{Name: "params"}
+	exprs := make(map[string]string)
+	ctxName := ""
+
+	for _, param := range params {
+		switch {
+		case param.Type == "context.Context":
+			ctxName = param.Name
+		case isDomainStructFunc(param.Type):
+			if strings.HasPrefix(param.Type, "[]") {
+				return "", nil, errUnsupportedSliceDomainStruct(param.Type)
+			}
+
+			for _, f := range sourceStructs[param.Type].Fields {
+				values.Fields = append(values.Fields, f)
+				exprs[f.Name] = param.Name + "." + f.Name
+			}
+		default:
+			values.Fields = append(values.Fields, FieldInfo{Name: param.Name, Type: param.Type})
+			exprs[param.Name] = param.Name
+		}
+	}
+
+	// Target scalars are matched as if they were the fields of a struct.
+	targetScalars := StructInfo{Name: targetMethod.Name}
+
+	for _, tp := range targetMethod.Params {
+		if tp.Type != "context.Context" && !isDomainStructFunc(tp.Type) {
+			targetScalars.Fields = append(targetScalars.Fields, FieldInfo{Name: tp.Name, Type: tp.Type})
+		}
+	}
+
+	args := make([]string, 0, len(targetMethod.Params))
+	scalarIdx := 0
+
+	var diags []Diagnostic
+
+	for _, tp := range targetMethod.Params {
+		switch {
+		case tp.Type == "context.Context":
+			args = append(args, ctxName)
+		case isDomainStructFunc(tp.Type):
+			targetStruct := lookupTargetStruct(targetStructs, engPkg, tp.Type)
+
+			fields := make([]string, 0, len(targetStruct.Fields))
+
+			for targetIdx, targetField := range targetStruct.Fields {
+				sf, strategy := findParamValue(targetField, targetIdx, targetStruct, values)
+				if d, ok := fieldDiagnostic(targetStruct, targetField, sf, strategy); ok {
+					diags = append(diags, d)
+				}
+
+				if strategy != matchNone {
+					fields = append(fields, generateFieldConversion(targetField.Name, targetField.Type, sf.Type, exprs[sf.Name]))
+				}
+			}
+
+			args = append(args, fmt.Sprintf("%s.%s{\n%s,\n}", engPkg, tp.Type, strings.Join(fields, ",\n")))
+		default:
+			sf, strategy := findParamValue(targetScalars.Fields[scalarIdx], scalarIdx, targetScalars, values)
+			if strategy == matchNone {
+				return "", nil, errUnmappedParam(targetMethod.Name, tp.Name)
+			}
+
+			if d, ok := fieldDiagnostic(targetScalars, targetScalars.Fields[scalarIdx], sf, strategy); ok {
+				diags = append(diags, d)
+			}
+
+			args = append(args, convertValue(tp.Type, sf.Type, exprs[sf.Name]))
+			scalarIdx++
+		}
+	}
+
+	return strings.Join(args, ", "), diags, nil
+}
+
 func joinParamsCall(
 	params []Param,
 	engPkg string,
 	targetMethod MethodInfo,
 	targetStructs map[string]StructInfo,
 	sourceStructs map[string]StructInfo,
-) (string, error) {
+) (string, []Diagnostic, error) {
+	if !paramsShapeMatches(params, targetMethod) {
+		return joinReshapedParams(params, engPkg, targetMethod, targetStructs, sourceStructs)
+	}
+
 	p := make([]string, 0, len(params))
 
+	var diags []Diagnostic
+
 	for i, param := range params {
 		if isDomainStructFunc(param.Type) {
-			result, err := joinDomainStructParam(param, i, engPkg, targetMethod, targetStructs, sourceStructs)
+			result, paramDiags, err := joinDomainStructParam(param, i, engPkg, targetMethod, targetStructs, sourceStructs)
 			if err != nil {
-				return "", err
+				return "", nil, err
 			}
 
 			p = append(p, result)
+			diags = append(diags, paramDiags...)
 		} else {
 			p = append(p, joinNonDomainParam(param, i, targetMethod))
 		}
 	}
 
-	return strings.Join(p, ", "), nil
+	return strings.Join(p, ", "), diags, nil
 }
 
 func joinReturns(returns []Return) string {
@@ -342,6 +843,21 @@ func joinReturns(returns []Return) string {
 	return strings.Join(r, ", ")
 }
 
+// joinNamedReturns is joinReturns with the error result named err and the others blank,
+// so that a deferred function can replace the returned error.
+func joinNamedReturns(returns []Return) string {
+	if len(returns) == 0 || returns[len(returns)-1].Type != "error" {
+		return joinReturns(returns)
+	}
+
+	r := make([]string, 0, len(returns))
+	for _, ret := range returns[:len(returns)-1] {
+		r = append(r, "_ "+ret.Type)
+	}
+
+	return strings.Join(append(r, "err error"), ", ")
+}
+
 func isSlice(retType string) bool {
 	return strings.HasPrefix(retType, "[]")
 }
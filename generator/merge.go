@@ -0,0 +1,197 @@
+package generator
+
+import (
+	"log"
+	"strings"
+)
+
+// selectMethods returns the source package data with the methods of the unified Querier
+// selected according to set. Methods taken from the engines in union mode are checked
+// for conflicting signatures, which are returned as problems.
+func selectMethods(
+	source PackageData,
+	engines []Engine,
+	engineData map[string]PackageData,
+	set MethodSet,
+) (PackageData, []validationProblem) {
+	switch set {
+	case MethodSetUnion:
+		return unionMethods(source, engines, engineData)
+	case MethodSetIntersection:
+		return intersectMethods(source, engines, engineData), nil
+	case "", MethodSetSource:
+		return source, nil
+	default:
+		log.Fatalf("unknown method set %q", set)
+
+		return source, nil
+	}
+}
+
+// unionMethods adds the methods that only exist on some engines to the source methods.
+// The first engine (in configured order) that does not return sql.Result provides the
+// signature, docs and structs of such a method; every other engine defining it must have
+// a compatible signature. Methods missing on some engines are restricted to the engines
+// that define them, as if annotated with @engines.
+func unionMethods(
+	source PackageData,
+	engines []Engine,
+	engineData map[string]PackageData,
+) (PackageData, []validationProblem) {
+	merged := PackageData{
+		Methods: append([]MethodInfo{}, source.Methods...),
+		Structs: make(map[string]StructInfo, len(source.Structs)),
+	}
+
+	for name, s := range source.Structs {
+		merged.Structs[name] = s
+	}
+
+	known := make(map[string]bool, len(source.Methods))
+	for _, m := range source.Methods {
+		known[m.Name] = true
+	}
+
+	var names []string
+
+	versions := make(map[string]map[string]MethodInfo)
+
+	for _, engine := range engines {
+		for _, m := range engineData[engine.Name].Methods {
+			if known[m.Name] {
+				continue
+			}
+
+			if _, seen := versions[m.Name]; !seen {
+				names = append(names, m.Name)
+				versions[m.Name] = make(map[string]MethodInfo)
+			}
+
+			versions[m.Name][engine.Name] = m
+		}
+	}
+
+	var problems []validationProblem
+
+	for _, name := range names {
+		canonical, canonicalEngine := canonicalMethod(engines, versions[name])
+
+		var present []string
+
+		for _, engine := range engines {
+			m, ok := versions[name][engine.Name]
+			if !ok {
+				continue
+			}
+
+			present = append(present, engine.Name)
+
+			if engine.Name == canonicalEngine.Name {
+				continue
+			}
+
+			for _, msg := range []string{validateArity(canonical, m), validateReturns(engine, canonical, m)} {
+				if msg != "" {
+					problems = append(problems, validationProblem{
+						Engine:  engine.Name,
+						Method:  name,
+						Message: "conflicts with " + canonicalEngine.Name + ": " + msg,
+					})
+				}
+			}
+		}
+
+		if len(present) < len(engines) && len(canonical.Engines) == 0 && len(canonical.EngineOnly) == 0 {
+			canonical.Engines = present
+		}
+
+		copyMethodStructs(merged.Structs, canonical, engineData[canonicalEngine.Name].Structs)
+		merged.Methods = append(merged.Methods, canonical)
+
+		log.Printf("Adding %s from %s\n", name, strings.Join(present, ", "))
+	}
+
+	return merged, problems
+}
+
+// canonicalMethod picks the version of a method used for the unified Querier.
+func canonicalMethod(engines []Engine, versions map[string]MethodInfo) (MethodInfo, Engine) {
+	var (
+		first       MethodInfo
+		firstEngine Engine
+	)
+
+	for _, engine := range engines {
+		m, ok := versions[engine.Name]
+		if !ok {
+			continue
+		}
+
+		if firstReturnType(m.Returns) != "sql.Result" {
+			return m, engine
+		}
+
+		if firstEngine.Name == "" {
+			first, firstEngine = m, engine
+		}
+	}
+
+	return first, firstEngine
+}
+
+// copyMethodStructs copies the engine structs referenced by m into structs, keeping any
+// struct already defined there.
+func copyMethodStructs(structs map[string]StructInfo, m MethodInfo, engineStructs map[string]StructInfo) {
+	types := make([]string, 0, len(m.Params)+len(m.Returns))
+
+	for _, p := range m.Params {
+		types = append(types, p.Type)
+	}
+
+	for _, r := range m.Returns {
+		types = append(types, r.Type)
+	}
+
+	for _, t := range types {
+		t = strings.TrimPrefix(t, "[]")
+		if _, exists := structs[t]; exists || !isDomainStruct(t) {
+			continue
+		}
+
+		if s, ok := engineStructs[t]; ok {
+			structs[t] = s
+		}
+	}
+}
+
+// intersectMethods keeps the source methods available on every engine. Methods annotated
+// with @engines, @skip-engine or @engine-only are expected to be partial and are kept.
+func intersectMethods(source PackageData, engines []Engine, engineData map[string]PackageData) PackageData {
+	kept := make([]MethodInfo, 0, len(source.Methods))
+
+	for _, m := range source.Methods {
+		if len(m.Engines) > 0 || len(m.SkipEngines) > 0 || len(m.EngineOnly) > 0 {
+			kept = append(kept, m)
+
+			continue
+		}
+
+		var missing []string
+
+		for _, engine := range engines {
+			if _, ok := findMethod(engineData[engine.Name].Methods, m.Name); !ok {
+				missing = append(missing, engine.Name)
+			}
+		}
+
+		if len(missing) > 0 {
+			log.Printf("Dropping %s: missing from %s\n", m.Name, strings.Join(missing, ", "))
+
+			continue
+		}
+
+		kept = append(kept, m)
+	}
+
+	return PackageData{Methods: kept, Structs: source.Structs}
+}
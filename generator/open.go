@@ -0,0 +1,74 @@
+package generator
+
+import (
+	"bytes"
+	"log"
+	"text/template"
+)
+
+// openFileName is the Open factory generated in the target package.
+const openFileName = generatedFilePrefix + "open.go"
+
+// openEngine describes how Open recognizes and connects to an engine.
+type openEngine struct {
+	Engine
+	Schemes []string // URL prefixes selecting the engine
+	Driver  string   // default database/sql driver name
+	Import  string   // package registering Driver, imported for its side effects
+	TrimDSN bool     // pass the URL without its scheme to sql.Open
+}
+
+// openEngines returns the Open configuration of every engine. Engines other than SQLite,
+// PostgreSQL and MySQL are selected by a "<name>://" prefix and use a driver of the same
+// name, which the user must register.
+func openEngines(engines []Engine) []openEngine {
+	result := make([]openEngine, 0, len(engines))
+
+	for _, engine := range engines {
+		switch {
+		case engine.IsSQLite():
+			result = append(result, openEngine{engine, []string{"sqlite:"}, "sqlite3", "github.com/mattn/go-sqlite3", true})
+		case engine.IsPostgres():
+			result = append(result, openEngine{
+				engine, []string{"postgres://", "postgresql://"}, "pgx", "github.com/jackc/pgx/v5/stdlib", false,
+			})
+		case engine.IsMySQL():
+			result = append(result, openEngine{engine, []string{"mysql://"}, "mysql", "github.com/go-sql-driver/mysql", true})
+		default:
+			result = append(result, openEngine{engine, []string{engine.Name + "://"}, engine.Name, "", true})
+		}
+	}
+
+	return result
+}
+
+// generateOpen renders the Open factory for the configured engines. declareErr controls
+// whether ErrUnsupportedDriver is declared, as users may already declare it themselves.
+func generateOpen(packageName string, engines []Engine, declareErr bool) []byte {
+	t := template.Must(template.New("open").Parse(openTemplate))
+
+	hasEngine := func(is func(Engine) bool) bool {
+		for _, engine := range engines {
+			if is(engine) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	var buf bytes.Buffer
+
+	data := map[string]interface{}{
+		"PackageName": packageName,
+		"Engines":     openEngines(engines),
+		"HasSQLite":   hasEngine(Engine.IsSQLite),
+		"HasPostgres": hasEngine(Engine.IsPostgres),
+		"DeclareErr":  declareErr,
+	}
+	if err := t.Execute(&buf, data); err != nil {
+		log.Fatalf("executing open template: %v", err)
+	}
+
+	return buf.Bytes()
+}
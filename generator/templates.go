@@ -28,21 +28,64 @@ import (
 
 type Querier interface {
 {{- range .Methods}}
+	{{- if not .EngineOnly}}
 	{{- range .Docs}}
 	{{.}}
 	{{- end}}
 	{{.Name}}({{joinParamsSignature .Params}}) ({{joinReturns .Returns}})
+	{{- end}}
 {{- end}}
 
 	WithTx(tx *sql.Tx) Querier
+	// WithDBTX returns a Querier running every query on db, such as a *sql.Conn pinned
+	// for session settings or advisory locks.
+	WithDBTX(db DBTX) Querier
+	// BeginTx starts a transaction, or a savepoint when the Querier is already in one.
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (TxQuerier, error)
 	DB() *sql.DB
 }
+
+// TxQuerier is a Querier scoped to a transaction started with BeginTx.
+type TxQuerier interface {
+	Querier
+	// Commit commits the transaction, or releases the savepoint of a nested BeginTx.
+	Commit() error
+	// Rollback rolls the transaction back, or rolls back to the savepoint of a nested
+	// BeginTx. It returns sql.ErrTxDone after Commit, so it can be deferred.
+	Rollback() error
+}
+
+// DBTX is what queries run on: a *sql.DB, *sql.Tx or *sql.Conn.
+type DBTX interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+{{range .Extensions}}
+// {{.InterfaceName}} extends Querier with the queries only available on {{.Engine.Name}}.
+// Obtain it with a type assertion on a Querier backed by {{.Engine.Name}}:
+//
+//	eq, ok := q.({{.InterfaceName}})
+type {{.InterfaceName}} interface {
+	Querier
+{{- range .Methods}}
+	{{- range .Docs}}
+	{{.}}
+	{{- end}}
+	{{.Name}}({{joinParamsSignature .Params}}) ({{joinReturns .Returns}})
+{{- end}}
+}
+{{end}}
 `
 
 const errorsTemplate = `// Code generated by sqlc-multi-db. DO NOT EDIT.
 package {{.PackageName}}
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
 
 var (
 	// ErrNotFound is returned when a query returns no rows.
@@ -50,7 +93,645 @@ var (
 
 	// ErrMismatchedSlices is returned when bulk operations receive slices of different lengths.
 	ErrMismatchedSlices = errors.New("mismatched slice lengths")
+
+	// ErrNotSupported is returned by methods that are not available on the current database engine.
+	ErrNotSupported = errors.New("not supported by this database engine")
+)
+
+// Classification sentinels. Wrappers wrap driver errors with the matching sentinel, so
+// errors.Is(err, ErrDuplicateKey) works on every engine while errors.As still reaches
+// the driver's own error type.
+var (
+	// ErrDuplicateKey is a unique or primary key constraint violation.
+	ErrDuplicateKey = errors.New("duplicate key")
+
+	// ErrForeignKeyViolation is a foreign key constraint violation.
+	ErrForeignKeyViolation = errors.New("foreign key violation")
+
+	// ErrCheckViolation is a CHECK constraint violation.
+	ErrCheckViolation = errors.New("check constraint violation")
+
+	// ErrNotNullViolation is a NOT NULL constraint violation.
+	ErrNotNullViolation = errors.New("not null violation")
+
+	// ErrDeadlock is a deadlock, a lock wait timeout or, on SQLite, a busy or locked database.
+	ErrDeadlock = errors.New("deadlock")
+
+	// ErrSerialization is a serialization failure of a concurrent transaction.
+	ErrSerialization = errors.New("serialization failure")
+)
+
+// classifiedError attaches a classification sentinel to a driver error.
+type classifiedError struct {
+	kind error
+	err  error
+}
+
+func (e *classifiedError) Error() string {
+	return e.err.Error()
+}
+
+func (e *classifiedError) Unwrap() []error {
+	return []error{e.kind, e.err}
+}
+
+// classify wraps err with kind, unless err is already classified.
+func classify(kind, err error) error {
+	var ce *classifiedError
+	if errors.As(err, &ce) {
+		return err
+	}
+
+	return &classifiedError{kind: kind, err: err}
+}
+
+// BulkError is returned when a bulk operation fails part way through. Index is the
+// element that failed; when elements are sent in chunks (multi-row INSERTs or
+// @bulk-chunk) it is the first element of the failing chunk. Err is the cause, which may
+// be the context's error if it was canceled between elements.
+type BulkError struct {
+	Method string
+	Index  int
+	Err    error
+}
+
+func (e *BulkError) Error() string {
+	return fmt.Sprintf("%s: element %d: %v", e.Method, e.Index, e.Err)
+}
+
+func (e *BulkError) Unwrap() error {
+	return e.Err
+}
+{{- if .QueryErrors}}
+
+// QueryError is returned by the wrappers for every failing call. It names the Querier
+// method and the engine that failed; errors.Is and errors.As see through it to Err.
+type QueryError struct {
+	Method string
+	Engine string
+	Err    error
+}
+
+func (e *QueryError) Error() string {
+	return fmt.Sprintf("%s: %s: %v", e.Engine, e.Method, e.Err)
+}
+
+func (e *QueryError) Unwrap() error {
+	return e.Err
+}
+
+// newQueryError wraps err, if any, in a QueryError.
+func newQueryError(engine, method string, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return &QueryError{Method: method, Engine: engine, Err: err}
+}
+
+// unwrapQueryError returns the error wrapped by a QueryError, so that a bulk method
+// calling its singular method reports a single QueryError.
+func unwrapQueryError(err error) error {
+	var qe *QueryError
+	if errors.As(err, &qe) {
+		return qe.Err
+	}
+
+	return err
+}
+{{- end}}
+`
+
+// mysqlErrorsTemplate renders mysqlErrorNumber for the MySQL classifier. Like the cgo split
+// of go-sqlite3, it is generated twice: with go-sql-driver/mysql by default, and without it
+// under the nomysqldriver build tag, where MySQL errors are left unclassified.
+const mysqlErrorsTemplate = `// Code generated by sqlc-multi-db. DO NOT EDIT.
+
+//go:build {{if .Driver}}!{{end}}nomysqldriver
+
+package {{.PackageName}}
+{{if .Driver}}
+import (
+	"errors"
+
+	"github.com/go-sql-driver/mysql"
 )
+
+// mysqlErrorNumber returns the number of the MySQL server error in err's chain.
+func mysqlErrorNumber(err error) (uint16, bool) {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		return mysqlErr.Number, true
+	}
+
+	return 0, false
+}
+{{- else}}
+// mysqlErrorNumber finds no MySQL server error: built without go-sql-driver/mysql, the
+// package cannot recognize its errors.
+func mysqlErrorNumber(error) (uint16, bool) { return 0, false }
+{{- end}}
+`
+
+const txTemplate = `// Code generated by sqlc-multi-db. DO NOT EDIT.
+package {{.PackageName}}
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"sync/atomic"
+	"time"
+)
+
+// TxRetry configures how RunInTx retries transactions that fail with ErrDeadlock or
+// ErrSerialization.
+type TxRetry struct {
+	MaxAttempts int           // attempts including the first one; less than 1 means 1
+	BaseDelay   time.Duration // delay before the first retry, doubled for every further retry
+	MaxDelay    time.Duration // upper bound of the delay, before jitter
+}
+
+// DefaultTxRetry is the retry policy of RunInTx.
+var DefaultTxRetry = TxRetry{MaxAttempts: 5, BaseDelay: 10 * time.Millisecond, MaxDelay: time.Second}
+
+// txSavepoints numbers the savepoints of nested RunInTx calls.
+var txSavepoints atomic.Uint64
+
+// txQuerier is implemented by the generated wrappers.
+type txQuerier interface {
+	beginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+	currentTx() *sql.Tx
+	txOptions(opts *sql.TxOptions) *sql.TxOptions
+	classifyError(err error) error
+}
+
+// RunInTx runs fn in a transaction on q's database, or on its *sql.Conn (see WithDBTX),
+// committing if fn returns nil and rolling back otherwise. The isolation level in opts is
+// mapped to the closest level the engine supports.
+//
+// When the transaction fails with ErrDeadlock or ErrSerialization, it is retried from the
+// start according to DefaultTxRetry, so fn must be safe to run more than once.
+//
+// When q is already scoped to a transaction (see WithTx), fn runs inside a savepoint of
+// that transaction instead: a failing fn only rolls back its own work, opts are ignored
+// and nothing is retried, as only the outermost transaction can be.
+func RunInTx(ctx context.Context, q Querier, opts *sql.TxOptions, fn func(Querier) error) error {
+	// A RoutingQuerier runs transactions on its primary.
+	if r, ok := q.(interface{ Primary() Querier }); ok {
+		q = r.Primary()
+	}
+
+	tq, _ := q.(txQuerier)
+	if tq != nil {
+		if tx := tq.currentTx(); tx != nil {
+			return runInSavepoint(ctx, tx, q, fn)
+		}
+
+		opts = tq.txOptions(opts)
+	}
+
+	attempts := max(DefaultTxRetry.MaxAttempts, 1)
+	delay := DefaultTxRetry.BaseDelay
+
+	for attempt := 1; ; attempt++ {
+		err := runTx(ctx, q, tq, opts, fn)
+		if err == nil || attempt == attempts ||
+			(!errors.Is(err, ErrDeadlock) && !errors.Is(err, ErrSerialization)) {
+			return err
+		}
+
+		wait := min(delay, DefaultTxRetry.MaxDelay)
+		if wait > 0 {
+			wait += rand.N(wait/2 + 1)
+		}
+
+		select {
+		case <-ctx.Done():
+			return errors.Join(err, ctx.Err())
+		case <-time.After(wait):
+		}
+
+		delay *= 2
+	}
+}
+
+// runTx runs a single attempt of RunInTx.
+func runTx(ctx context.Context, q Querier, tq txQuerier, opts *sql.TxOptions, fn func(Querier) error) error {
+	classify := func(err error) error {
+		if tq == nil {
+			return err
+		}
+
+		return tq.classifyError(err)
+	}
+
+	begin := q.DB().BeginTx
+	if tq != nil {
+		begin = tq.beginTx
+	}
+
+	tx, err := begin(ctx, opts)
+	if err != nil {
+		return classify(err)
+	}
+
+	// Rolls back if fn fails or panics; a no-op after Commit.
+	defer func() { _ = tx.Rollback() }()
+
+	if err := fn(q.WithTx(tx)); err != nil {
+		return err
+	}
+
+	return classify(tx.Commit())
+}
+
+// runInSavepoint runs fn inside a savepoint of tx.
+func runInSavepoint(ctx context.Context, tx *sql.Tx, q Querier, fn func(Querier) error) error {
+	name := fmt.Sprintf("sqlc_multi_db_tx_%d", txSavepoints.Add(1))
+
+	if _, err := tx.ExecContext(ctx, "SAVEPOINT "+name); err != nil {
+		return err
+	}
+
+	if err := fn(q); err != nil {
+		_, _ = tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+name)
+		_, _ = tx.ExecContext(ctx, "RELEASE SAVEPOINT "+name)
+
+		return err
+	}
+
+	_, err := tx.ExecContext(ctx, "RELEASE SAVEPOINT "+name)
+
+	return err
+}
+`
+
+const routingTemplate = `// Code generated by sqlc-multi-db. DO NOT EDIT.
+package {{.PackageName}}
+
+import (
+	"context"
+	"database/sql"
+)
+
+// RoutingQuerier is a Querier sending read-only queries to a replica and every other
+// query to the primary. Queries are classified when generating: a SELECT without locking
+// clauses is read-only, unless annotated with @primary, and @replica forces a query to
+// the replica.
+//
+// Transactions always run on the primary: WithTx, WithDBTX and BeginTx return a Querier
+// of the primary, and RunInTx runs on the primary.
+type RoutingQuerier struct {
+	primary Querier
+	replica Querier
+}
+
+// NewRoutingQuerier returns a RoutingQuerier sending reads to replica and everything else
+// to primary.
+func NewRoutingQuerier(primary, replica Querier) *RoutingQuerier {
+	return &RoutingQuerier{primary: primary, replica: replica}
+}
+
+// Primary returns the Querier of the primary.
+func (r *RoutingQuerier) Primary() Querier { return r.primary }
+
+// Replica returns the Querier of the replica.
+func (r *RoutingQuerier) Replica() Querier { return r.replica }
+{{range .Methods}}
+{{- if not .EngineOnly}}
+func (r *RoutingQuerier) {{.Name}}({{joinParamsSignature .Params}}) ({{joinReturns .Returns}}) {
+	{{if .Returns}}return {{end}}r.{{if .ReadsFromReplica}}replica{{else}}primary{{end}}.{{.Name}}(
+		{{- range $i, $p := .Params}}{{if $i}}, {{end}}{{$p.Name}}{{end}})
+}
+{{end}}
+{{- end}}
+func (r *RoutingQuerier) WithTx(tx *sql.Tx) Querier { return r.primary.WithTx(tx) }
+
+func (r *RoutingQuerier) WithDBTX(db DBTX) Querier { return r.primary.WithDBTX(db) }
+
+func (r *RoutingQuerier) BeginTx(ctx context.Context, opts *sql.TxOptions) (TxQuerier, error) {
+	return r.primary.BeginTx(ctx, opts)
+}
+
+func (r *RoutingQuerier) DB() *sql.DB { return r.primary.DB() }
+`
+
+const adapterTemplate = `// Code generated by sqlc-multi-db. DO NOT EDIT.
+package {{.Package}}
+
+import (
+	"database/sql"
+)
+
+// Adapter wraps {{.Package}}.Queries and provides the DB() method.
+type Adapter struct {
+	*Queries
+	db *sql.DB
+}
+
+// NewAdapter creates a new {{.Engine.ExportedName}} adapter.
+func NewAdapter(db *sql.DB) *Adapter {
+	return &Adapter{
+		Queries: New(db),
+		db:      db,
+	}
+}
+
+// DB returns the underlying database connection.
+func (a *Adapter) DB() *sql.DB {
+	return a.db
+}
+
+// WithTx returns a new Adapter with the queries scoped to the given transaction.
+func (a *Adapter) WithTx(tx *sql.Tx) *Adapter {
+	return &Adapter{
+		Queries: a.Queries.WithTx(tx),
+		db:      a.db,
+	}
+}
+
+// WithDBTX returns a new Adapter with the queries running on db, e.g. a *sql.Conn.
+func (a *Adapter) WithDBTX(db DBTX) *Adapter {
+	return &Adapter{
+		Queries: New(db),
+		db:      a.db,
+	}
+}
+
+// DBTX returns the current database executor.
+func (a *Adapter) DBTX() DBTX {
+	return a.Queries.db
+}
+`
+
+const openTemplate = `// Code generated by sqlc-multi-db. DO NOT EDIT.
+{{if .HasPostgres}}
+//go:build !js
+{{- end}}
+package {{.PackageName}}
+
+import (
+	"context"
+	"database/sql"
+	{{- if .HasSQLite}}
+	"database/sql/driver"
+	{{- end}}
+	{{- if or .DeclareErr .HasSQLite}}
+	"errors"
+	{{- end}}
+	"fmt"
+	"strings"
+	"time"
+{{range .Engines}}{{if .Import}}
+	_ "{{.Import}}" // {{.ExportedName}} driver
+{{- end}}{{end}}
+)
+{{if .DeclareErr}}
+// ErrUnsupportedDriver is returned by Open when the URL scheme matches no configured engine.
+var ErrUnsupportedDriver = errors.New("unsupported database driver")
+{{end}}
+// Option configures the connection opened by Open.
+type Option func(*openOptions)
+
+type openOptions struct {
+	maxOpenConns    *int
+	maxIdleConns    int
+	connMaxLifetime time.Duration
+	connMaxIdleTime time.Duration
+{{- range .Engines}}
+	{{.Name}}Driver string
+{{- end}}
+{{- if .HasSQLite}}
+	sqlitePragmas     []string
+	sqliteReaderConns int
+{{- end}}
+}
+
+// WithMaxOpenConns sets the maximum number of open connections (see sql.DB.SetMaxOpenConns).
+{{- if .HasSQLite}}
+// On SQLite it applies to the writer, which defaults to a single connection.
+{{- end}}
+func WithMaxOpenConns(n int) Option {
+	return func(o *openOptions) { o.maxOpenConns = &n }
+}
+
+// WithMaxIdleConns sets the maximum number of idle connections (see sql.DB.SetMaxIdleConns).
+func WithMaxIdleConns(n int) Option {
+	return func(o *openOptions) { o.maxIdleConns = n }
+}
+
+// WithConnMaxLifetime sets how long a connection may be reused (see sql.DB.SetConnMaxLifetime).
+func WithConnMaxLifetime(d time.Duration) Option {
+	return func(o *openOptions) { o.connMaxLifetime = d }
+}
+
+// WithConnMaxIdleTime sets how long a connection may stay idle (see sql.DB.SetConnMaxIdleTime).
+func WithConnMaxIdleTime(d time.Duration) Option {
+	return func(o *openOptions) { o.connMaxIdleTime = d }
+}
+{{range .Engines}}
+// With{{.ExportedName}}Driver sets the database/sql driver used for {{.ExportedName}} URLs
+// (default {{printf "%q" .Driver}}). The driver must be registered by the caller.
+func With{{.ExportedName}}Driver(name string) Option {
+	return func(o *openOptions) { o.{{.Name}}Driver = name }
+}
+{{end}}
+{{- if .HasSQLite}}
+// WithSQLitePragmas replaces the PRAGMA statements run on every new SQLite connection
+// (default "foreign_keys = ON" and "busy_timeout = 5000").
+func WithSQLitePragmas(pragmas ...string) Option {
+	return func(o *openOptions) { o.sqlitePragmas = pragmas }
+}
+
+// WithSQLiteReaderConns sets the size of the SQLite reader pool (default 4). Zero runs
+// every query on the single writer connection.
+func WithSQLiteReaderConns(n int) Option {
+	return func(o *openOptions) { o.sqliteReaderConns = n }
+}
+{{end}}
+// Open opens a database connection and returns a Querier for the engine selected by the
+// URL scheme:
+{{- range .Engines}}
+//   - {{range $i, $s := .Schemes}}{{if $i}}, {{end}}{{$s}}{{end}} ({{.ExportedName}})
+{{- end}}
+//
+// Any other scheme returns ErrUnsupportedDriver.
+{{- if .HasSQLite}}
+//
+// A SQLite file is opened twice: a single-connection writer in WAL mode, and a pool of
+// read-only connections used for read-only queries outside transactions. In-memory
+// databases only get the writer.
+{{- end}}
+func Open(ctx context.Context, dbURL string, opts ...Option) (Querier, error) {
+	o := openOptions{
+{{- range .Engines}}
+		{{.Name}}Driver: {{printf "%q" .Driver}},
+{{- end}}
+{{- if .HasSQLite}}
+		sqlitePragmas:     []string{"foreign_keys = ON", "busy_timeout = 5000"},
+		sqliteReaderConns: 4,
+{{- end}}
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	switch {
+{{- range .Engines}}
+	case {{range $i, $s := .Schemes}}{{if $i}}, {{end}}strings.HasPrefix(dbURL, {{printf "%q" $s}}){{end}}:
+{{- if .IsSQLite}}
+		return o.openSQLite(ctx, strings.TrimPrefix(dbURL, {{printf "%q" (index .Schemes 0)}}))
+{{- else}}
+		sdb, err := sql.Open(o.{{.Name}}Driver, {{if .TrimDSN}}strings.TrimPrefix(dbURL, {{printf "%q" (index .Schemes 0)}}){{else}}dbURL{{end}})
+		if err != nil {
+			return nil, fmt.Errorf("opening {{.Name}}: %w", err)
+		}
+
+		o.configure(sdb, 0)
+
+		return New{{.ExportedName}}Querier(sdb), nil
+{{- end}}
+{{end}}
+	default:
+		return nil, ErrUnsupportedDriver
+	}
+}
+
+// configure applies the pool options to sdb. defaultMaxOpenConns is used unless
+// WithMaxOpenConns was given; zero means unlimited.
+func (o openOptions) configure(sdb *sql.DB, defaultMaxOpenConns int) {
+	maxOpenConns := defaultMaxOpenConns
+	if o.maxOpenConns != nil {
+		maxOpenConns = *o.maxOpenConns
+	}
+
+	sdb.SetMaxOpenConns(maxOpenConns)
+
+	if o.maxIdleConns != 0 {
+		sdb.SetMaxIdleConns(o.maxIdleConns)
+	}
+
+	if o.connMaxLifetime != 0 {
+		sdb.SetConnMaxLifetime(o.connMaxLifetime)
+	}
+
+	if o.connMaxIdleTime != 0 {
+		sdb.SetConnMaxIdleTime(o.connMaxIdleTime)
+	}
+}
+{{- if .HasSQLite}}
+
+// openSQLite opens the SQLite writer and, for a database file, the reader pool.
+func (o openOptions) openSQLite(ctx context.Context, dsn string) (Querier, error) {
+	split := o.sqliteReaderConns > 0 && !sqliteInMemory(dsn)
+
+	writerPragmas := o.sqlitePragmas
+	if split {
+		writerPragmas = append(writerPragmas[:len(writerPragmas):len(writerPragmas)], "journal_mode = WAL")
+	}
+
+	writer, err := o.openSQLiteDB(ctx, dsn, writerPragmas)
+	if err != nil {
+		return nil, fmt.Errorf("opening sqlite: %w", err)
+	}
+
+	o.configure(writer, 1)
+
+	if !split {
+		return NewSQLiteQuerier(writer), nil
+	}
+
+	readerPragmas := append(o.sqlitePragmas[:len(o.sqlitePragmas):len(o.sqlitePragmas)], "query_only = ON")
+
+	reader, err := o.openSQLiteDB(ctx, dsn, readerPragmas)
+	if err != nil {
+		_ = writer.Close()
+
+		return nil, fmt.Errorf("opening sqlite reader: %w", err)
+	}
+
+	reader.SetMaxOpenConns(o.sqliteReaderConns)
+	reader.SetMaxIdleConns(o.sqliteReaderConns)
+
+	return NewSQLiteQuerierWithReader(writer, reader), nil
+}
+
+// openSQLiteDB opens a pool whose connections all run pragmas when they are created, and
+// connects once so that a bad path or PRAGMA is reported by Open.
+func (o openOptions) openSQLiteDB(ctx context.Context, dsn string, pragmas []string) (*sql.DB, error) {
+	probe, err := sql.Open(o.sqliteDriver, dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	drv := probe.Driver()
+	_ = probe.Close()
+
+	sdb := sql.OpenDB(sqlitePragmaConnector{driver: drv, dsn: dsn, pragmas: pragmas})
+	if err := sdb.PingContext(ctx); err != nil {
+		_ = sdb.Close()
+
+		return nil, err
+	}
+
+	return sdb, nil
+}
+
+// sqliteInMemory reports whether dsn names an in-memory database, which is private to a
+// connection and cannot be shared by a reader pool.
+func sqliteInMemory(dsn string) bool {
+	return dsn == "" || strings.Contains(dsn, ":memory:") || strings.Contains(dsn, "mode=memory")
+}
+
+// sqlitePragmaConnector runs PRAGMA statements on every connection it opens, as SQLite
+// PRAGMAs such as foreign_keys and busy_timeout only apply to the connection running them.
+type sqlitePragmaConnector struct {
+	driver  driver.Driver
+	dsn     string
+	pragmas []string
+}
+
+func (c sqlitePragmaConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.driver.Open(c.dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, pragma := range c.pragmas {
+		if err := execPragma(ctx, conn, "PRAGMA "+pragma); err != nil {
+			_ = conn.Close()
+
+			return nil, fmt.Errorf("running PRAGMA %s: %w", pragma, err)
+		}
+	}
+
+	return conn, nil
+}
+
+func (c sqlitePragmaConnector) Driver() driver.Driver { return c.driver }
+
+func execPragma(ctx context.Context, conn driver.Conn, query string) error {
+	if execer, ok := conn.(driver.ExecerContext); ok {
+		_, err := execer.ExecContext(ctx, query, nil)
+		if !errors.Is(err, driver.ErrSkip) {
+			return err
+		}
+	}
+
+	stmt, err := conn.Prepare(query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(nil)
+
+	return err
+}
+{{- end}}
 `
 
 const wrapperTemplate = `// Code generated by sqlc-multi-db. DO NOT EDIT.
@@ -70,18 +751,180 @@ import (
 // {{.Engine.Name}}Wrapper wraps the {{.Engine.Name}} adapter.
 type {{.Engine.Name}}Wrapper struct {
 	adapter *{{.Engine.Package}}.Adapter
+{{- if .Engine.IsSQLite}}
+	reader  *{{.Engine.Package}}.Adapter // read-only pool for read-only queries; nil sends them to adapter
+{{- end}}
+}
+
+// New{{.Engine.ExportedName}}Querier returns a Querier running queries on the {{.Engine.ExportedName}} database db.
+func New{{.Engine.ExportedName}}Querier(db *sql.DB) Querier {
+	return &{{.Engine.Name}}Wrapper{adapter: {{.Engine.Package}}.NewAdapter(db)}
+}
+
+// New{{.Engine.ExportedName}}QuerierFromAdapter returns a Querier running queries through an
+// existing {{.Engine.Package}}.Adapter, e.g. one scoped to a transaction with WithTx.
+func New{{.Engine.ExportedName}}QuerierFromAdapter(adapter *{{.Engine.Package}}.Adapter) Querier {
+	return &{{.Engine.Name}}Wrapper{adapter: adapter}
 }
+{{- if .Engine.IsSQLite}}
+
+// NewSQLiteQuerierWithReader returns a Querier that runs read-only queries on reader and
+// everything else, including transactions, on writer. Both must open the same database;
+// writer should be limited to a single connection and the database should use WAL so
+// that readers do not block the writer.
+func NewSQLiteQuerierWithReader(writer, reader *sql.DB) Querier {
+	return &sqliteWrapper{adapter: {{.Engine.Package}}.NewAdapter(writer), reader: {{.Engine.Package}}.NewAdapter(reader)}
+}
+
+// readAdapter returns the adapter running read-only queries.
+func (w *sqliteWrapper) readAdapter() *{{.Engine.Package}}.Adapter {
+	if w.reader != nil {
+		return w.reader
+	}
+
+	return w.adapter
+}
+{{- end}}
+{{range .Extensions}}
+var _ {{.InterfaceName}} = (*{{$.Engine.Name}}Wrapper)(nil)
+{{end}}
+
+// {{.Engine.Name}}BulkSavepoints numbers the savepoints of runBulk calls inside an existing
+// transaction, so that bulk calls nested in BeginTx or RunInTx savepoints never share a name.
+var {{.Engine.Name}}BulkSavepoints atomic.Uint64
+{{if not .Engine.IsPostgres}}
+// {{.Engine.Name}}MaxPlaceholders is the number of bind parameters a single statement may use.
+const {{.Engine.Name}}MaxPlaceholders = {{if .Engine.IsMySQL}}65535{{else}}32766{{end}}
+{{end}}
+// runBulk runs a bulk operation made of several statements atomically. Outside a
+// transaction it begins one (see beginTx); inside WithTx it uses a savepoint so a failed
+// bulk call is rolled back without aborting the caller's transaction.
+func (w *{{.Engine.Name}}Wrapper) runBulk(ctx context.Context, fn func(a *{{.Engine.Package}}.Adapter) error) error {
+	if tx, ok := w.adapter.DBTX().(*sql.Tx); ok {
+		name := fmt.Sprintf("sqlc_multi_db_bulk_%d", {{.Engine.Name}}BulkSavepoints.Add(1))
+
+		if _, err := tx.ExecContext(ctx, "SAVEPOINT "+name); err != nil {
+			return err
+		}
+
+		if err := fn(w.adapter); err != nil {
+			_, _ = tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+name)
+			_, _ = tx.ExecContext(ctx, "RELEASE SAVEPOINT "+name)
 
+			return err
+		}
+
+		_, err := tx.ExecContext(ctx, "RELEASE SAVEPOINT "+name)
+
+		return err
+	}
+
+	tx, err := w.beginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := fn(w.adapter.WithTx(tx)); err != nil {
+		_ = tx.Rollback()
+
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// classify{{.Engine.ExportedName}}Error wraps a {{.Engine.ExportedName}} driver error with the matching
+// classification sentinel, such as ErrDuplicateKey. Other errors are returned unchanged.
+{{- if .Engine.IsMySQL}}
+// The driver error is read by mysqlErrorNumber, generated in its own build-tagged file.
+{{- else}}
+// No driver is imported: any driver of the engine is recognized.
+{{- end}}
+func classify{{.Engine.ExportedName}}Error(err error) error {
+{{- if .Engine.IsSQLite}}
+	if err == nil {
+		return nil
+	}
+
+	// The messages come from SQLite itself, so they are the same with every driver.
+	msg := err.Error()
+
+	switch {
+	case strings.Contains(msg, "UNIQUE constraint failed"):
+		return classify(ErrDuplicateKey, err)
+	case strings.Contains(msg, "FOREIGN KEY constraint failed"):
+		return classify(ErrForeignKeyViolation, err)
+	case strings.Contains(msg, "CHECK constraint failed"):
+		return classify(ErrCheckViolation, err)
+	case strings.Contains(msg, "NOT NULL constraint failed"):
+		return classify(ErrNotNullViolation, err)
+	case strings.Contains(msg, "database is locked"), strings.Contains(msg, "database table is locked"):
+		return classify(ErrDeadlock, err)
+	}
+{{- else if .Engine.IsPostgres}}
+	// Implemented by both pgx's *pgconn.PgError and lib/pq's *pq.Error.
+	var pgErr interface{ SQLState() string }
+	if !errors.As(err, &pgErr) {
+		return err
+	}
+
+	switch pgErr.SQLState() {
+	case "23505": // unique_violation
+		return classify(ErrDuplicateKey, err)
+	case "23503": // foreign_key_violation
+		return classify(ErrForeignKeyViolation, err)
+	case "23514": // check_violation
+		return classify(ErrCheckViolation, err)
+	case "23502": // not_null_violation
+		return classify(ErrNotNullViolation, err)
+	case "40P01": // deadlock_detected
+		return classify(ErrDeadlock, err)
+	case "40001": // serialization_failure
+		return classify(ErrSerialization, err)
+	}
+{{- else if .Engine.IsMySQL}}
+	number, ok := mysqlErrorNumber(err)
+	if !ok {
+		return err
+	}
+
+	switch number {
+	case 1062, 1586: // ER_DUP_ENTRY, ER_DUP_ENTRY_WITH_KEY_NAME
+		return classify(ErrDuplicateKey, err)
+	case 1216, 1217, 1451, 1452: // ER_NO_REFERENCED_ROW, ER_ROW_IS_REFERENCED (and _2)
+		return classify(ErrForeignKeyViolation, err)
+	case 3819: // ER_CHECK_CONSTRAINT_VIOLATED
+		return classify(ErrCheckViolation, err)
+	case 1048, 1364: // ER_BAD_NULL_ERROR, ER_NO_DEFAULT_FOR_FIELD
+		return classify(ErrNotNullViolation, err)
+	case 1205, 1213: // ER_LOCK_WAIT_TIMEOUT, ER_LOCK_DEADLOCK
+		return classify(ErrDeadlock, err)
+	}
+{{- end}}
+
+	return err
+}
 {{range .Methods}}
 {{- $method := . -}}
 {{- $methodParams := .Params }}
-func (w *{{$.Engine.Name}}Wrapper) {{.Name}}({{joinParamsSignature .Params}}) ({{joinReturns .Returns}}) {
+func (w *{{$.Engine.Name}}Wrapper) {{.Name}}({{joinParamsSignature .Params}}) ({{joinNamedReturns .Returns}}) {
+	{{- if .ReturnsError}}
+		{{- $err := printf "classify%sError(err)" $.Engine.ExportedName}}
+		{{- if $.QueryErrors}}
+			{{- $err = printf "newQueryError(%q, %q, %s)" $.Engine.Name .Name $err}}
+		{{- end}}
+	defer func() { err = {{$err}} }()
+	{{- end}}
+	{{- if not (.SupportsEngine $.Engine.Name)}}
+	// {{.Name}} is not available on {{$.Engine.Name}}.
+	return {{template "zeroValues" .}}ErrNotSupported
+	{{- else}}
 	/* --- Auto-Loop for Bulk Insert on Non-Postgres --- */
 	{{$isAutoLoop := false}}
 	{{$singularMethodName := ""}}
 	{{- $paramType := "" -}}
 	{{- $sliceField := dict "Name" "" -}}
-	{{- if and (not $.Engine.IsPostgres) (gt (len .Params) 1) -}}
+	{{- if and (not $.Engine.IsPostgres) (gt (len .Params) 1) (ne .BulkStrategy "json") -}}
 		{{- $pType := (index .Params 1).Type -}}
 		{{- $sInfo := getStruct $pType -}}
 		{{- if hasSliceField $sInfo -}}
@@ -90,17 +933,6 @@ func (w *{{$.Engine.Name}}Wrapper) {{.Name}}({{joinParamsSignature .Params}}) ({
 				{{- $singularMethodName = .BulkFor -}}
 				{{- $paramType = $pType -}}
 				{{- $sliceField = getSliceField $sInfo -}}
-			{{- else if hasSuffix .Name "s" -}}
-				{{- $singularMethodName = toSingular .Name -}}
-				{{- if ne $singularMethodName .Name -}}
-					{{- $singularParamType := printf "%sParams" $singularMethodName -}}
-					{{- $sInfoSingular := getStruct $singularParamType -}}
-					{{- if ne $sInfoSingular.Name "" -}}
-						{{- $isAutoLoop = true -}}
-						{{- $paramType = $pType -}}
-						{{- $sliceField = getSliceField $sInfo -}}
-					{{- end -}}
-				{{- end -}}
 			{{- end -}}
 		{{- end -}}
 	{{- end -}}
@@ -109,73 +941,200 @@ func (w *{{$.Engine.Name}}Wrapper) {{.Name}}({{joinParamsSignature .Params}}) ({
 		{{- $bulkParamType := (index .Params 1).Type -}}
 		{{- $bulkStructInfo := getStruct $bulkParamType -}}
 		{{- $singularParamType := printf "%sParams" $singularMethodName -}}
-		{{- $targetSingularParamType := $singularParamType -}} {{/* Assume same name for now */}}
+		{{- $targetSingularParamType := $singularParamType -}}
+		{{- $targetSingularMethod := getTargetMethod $singularMethodName -}}
+		{{- if gt (len $targetSingularMethod.Params) 1 -}}
+			{{- $targetSingularParamType = (index $targetSingularMethod.Params 1).Type -}}
+		{{- end -}}
 		{{- $targetStructInfo := getTargetStruct $targetSingularParamType -}}
 		{{/* Check for mismatched slice lengths */}}
 		{{- range $bulkStructInfo.Fields}}
 			{{- if and (isSlice .Type) (ne .Name $sliceField.Name) }}
 		if len({{(index $methodParams 1).Name}}.{{.Name}}) != len({{(index $methodParams 1).Name}}.{{$sliceField.Name}}) {
-			{{$retType := firstReturnType $method.Returns}}
-			return {{if $method.ReturnsSelf}}nil, {{else if not $method.HasValue}}{{else if isSlice $retType}}nil, {{else if isDomainStruct $method.ReturnElem}}{{$method.ReturnElem}}{}, {{else}}{{zeroValue $retType}}, {{end}}ErrMismatchedSlices
+			return {{template "zeroValues" $method}}ErrMismatchedSlices
 		}
 			{{- end}}
 		{{- end}}
-		for i, v := range {{(index .Params 1).Name}}.{{$sliceField.Name}} {
-			_ = i
-			err := w.adapter.{{$singularMethodName}}({{(index .Params 0).Name}}, {{$.Engine.Package}}.{{$targetSingularParamType}}{
-				{{range $targetIdx, $targetField := $targetStructInfo.Fields}}
-					{{/* Find matching field in bulk (source) struct by name */}}
-					{{$sourceField := dict "Name" ""}}
-					{{$matchedIdx := -1}}
-					{{range $bulkIdx, $bulkField := $bulkStructInfo.Fields}}
-						{{if eq $bulkField.Name $targetField.Name}}
-							{{$sourceField = $bulkField}}
-							{{$matchedIdx = $bulkIdx}}
-						{{end}}
-						{{if and (eq $sourceField.Name "") (eq (toSingular $bulkField.Name) $targetField.Name)}}
-							{{$sourceField = $bulkField}}
-							{{$matchedIdx = $bulkIdx}}
-						{{end}}
-					{{end}}
-					{{/* Fall back to positional matching if name matching failed */}}
-					{{if eq $sourceField.Name ""}}
-						{{$sourceField = index $bulkStructInfo.Fields $targetIdx}}
-						{{$matchedIdx = $targetIdx}}
-					{{end}}
-					{{if ne $sourceField.Name ""}}
-						{{$srcExpr := ""}}
-						{{if eq $sourceField.Name $sliceField.Name}}
-							{{$srcExpr = "v"}}
-						{{else if isSlice $sourceField.Type}}
-							{{$srcExpr = printf "%s.%s[i]" (index $methodParams 1).Name $sourceField.Name}}
-						{{else}}
-							{{$srcExpr = printf "%s.%s" (index $methodParams 1).Name $sourceField.Name}}
-						{{end}}
-						{{$srcType := $sourceField.Type}}
-						{{if or (eq $sourceField.Name $sliceField.Name) (isSlice $sourceField.Type)}}
-							{{$srcType = trimPrefix $srcType "[]"}}
-						{{end}}
-						{{generateFieldConversion $targetField.Name $targetField.Type $srcType $srcExpr}},
-					{{end}}
-				{{end}}
-				},
-			)
-			if err != nil {
-				{{$retType := firstReturnType .Returns}}
-				return {{if .ReturnsSelf}}nil, {{else if not .HasValue}}{{else if isSlice $retType}}nil, {{else if isDomainStruct .ReturnElem}}{{.ReturnElem}}{}, {{else}}{{zeroValue $retType}}, {{end}}err
+		{{- $insert := bulkInsert .BulkStrategy $singularMethodName}}
+		{{- $bulkArg := (index .Params 1).Name}}
+		{{- if .HasValue}}
+		{{- $singular := getMethod $singularMethodName}}
+		{{- $retType := firstReturnType .Returns}}
+		{{- $singularRet := firstReturnType $singular.Returns}}
+		{{- $singularParam := ""}}
+		{{- if gt (len $singular.Params) 1}}
+			{{- $singularParam = (index $singular.Params 1).Type}}
+		{{- end}}
+		{{- $singularStruct := getStruct $singularParam}}
+		var items []{{trimPrefix $retType "[]"}}
+
+		err = w.runBulk({{(index .Params 0).Name}}, func(a *{{$.Engine.Package}}.Adapter) error {
+			// Call the wrapper's own {{$singularMethodName}} so its results are converted
+			// the same way as a direct call.
+			tw := &{{$.Engine.Name}}Wrapper{adapter: a}
+			items = make([]{{trimPrefix $retType "[]"}}, 0, len({{$bulkArg}}.{{$sliceField.Name}}))
+			for i, v := range {{$bulkArg}}.{{$sliceField.Name}} {
+				if err := {{(index $methodParams 0).Name}}.Err(); err != nil {
+					return &BulkError{Method: "{{$method.Name}}", Index: i, Err: err}
+				}
+
+				{{- if ne $singularStruct.Name ""}}
+				item, err := tw.{{$singularMethodName}}({{(index $methodParams 0).Name}}, {{$singularParam}}{
+					{{- range bulkLoopFields $method.Name $singularStruct $bulkStructInfo $sliceField $bulkArg}}
+					{{.}},
+					{{- end}}
+				})
+				{{- else}}
+				item, err := tw.{{$singularMethodName}}({{(index $methodParams 0).Name}}, v)
+				{{- end}}
+				if err != nil {
+					{{- if $.QueryErrors}}
+					// The bulk method's own QueryError wraps this BulkError.
+					return &BulkError{Method: "{{$method.Name}}", Index: i, Err: unwrapQueryError(err)}
+					{{- else}}
+					return &BulkError{Method: "{{$method.Name}}", Index: i, Err: err}
+					{{- end}}
+				}
+				items = append(items, item{{if isSlice $singularRet}}...{{end}})
 			}
+			return nil
+		})
+		if err != nil {
+			return {{template "zeroValues" .}}err
 		}
-		return nil
+		{{- if isSlice $retType}}
+		return items, nil
+		{{- else}}
+		// Like a :one query on PostgreSQL, return the first row.
+		if len(items) == 0 {
+			return {{template "zeroValues" .}}ErrNotFound
+		}
+		return items[0], nil
+		{{- end}}
+		{{- else if $insert.Placeholders}}
+		return w.runBulk({{(index .Params 0).Name}}, func(a *{{$.Engine.Package}}.Adapter) error {
+			// {{$singularMethodName}} is repeated as a multi-row INSERT, chunked to stay
+			// within the engine's placeholder limit.
+			const rowPlaceholders = {{$insert.Placeholders}}
+
+			chunkSize := {{$.Engine.Name}}MaxPlaceholders / rowPlaceholders
+			for start := 0; start < len({{$bulkArg}}.{{$sliceField.Name}}); start += chunkSize {
+				if err := {{(index $methodParams 0).Name}}.Err(); err != nil {
+					return &BulkError{Method: "{{$method.Name}}", Index: start, Err: err}
+				}
+
+				end := min(start+chunkSize, len({{$bulkArg}}.{{$sliceField.Name}}))
+				args := make([]any, 0, (end-start)*rowPlaceholders)
+				for i := start; i < end; i++ {
+					v := {{$bulkArg}}.{{$sliceField.Name}}[i]
+					row := {{$.Engine.Package}}.{{$targetSingularParamType}}{
+						{{- range bulkLoopFields $method.Name $targetStructInfo $bulkStructInfo $sliceField $bulkArg}}
+						{{.}},
+						{{- end}}
+					}
+					args = append(args{{range $targetStructInfo.Fields}}, row.{{.Name}}{{end}})
+				}
+
+				query := {{printf "%q" $insert.Prefix}} + {{printf "%q" $insert.Row}} +
+					strings.Repeat({{printf "%q" (printf ", %s" $insert.Row)}}, end-start-1){{if $insert.Suffix}} + {{printf "%q" $insert.Suffix}}{{end}}
+				if _, err := a.DBTX().ExecContext({{(index $methodParams 0).Name}}, query, args...); err != nil {
+					return &BulkError{Method: "{{$method.Name}}", Index: start, Err: err}
+				}
+			}
+			return nil
+		})
+		{{- else}}
+		return w.runBulk({{(index .Params 0).Name}}, func(a *{{$.Engine.Package}}.Adapter) error {
+			for i, v := range {{(index .Params 1).Name}}.{{$sliceField.Name}} {
+				if err := {{(index $methodParams 0).Name}}.Err(); err != nil {
+					return &BulkError{Method: "{{$method.Name}}", Index: i, Err: err}
+				}
+
+				err := a.{{$singularMethodName}}({{(index .Params 0).Name}}, {{$.Engine.Package}}.{{$targetSingularParamType}}{
+					{{- range bulkLoopFields $method.Name $targetStructInfo $bulkStructInfo $sliceField (index $methodParams 1).Name}}
+					{{.}},
+					{{- end}}
+				})
+				if err != nil {
+					return &BulkError{Method: "{{$method.Name}}", Index: i, Err: err}
+				}
+			}
+			return nil
+		})
+		{{- end}}
 	{{else}}
-		{{template "standardBody" (dict "Method" . "Engine" $.Engine "Structs" $.Structs "PackageName" $.PackageName)}}
+		{{- $jsonBulk := and (not $.Engine.IsPostgres) (eq .BulkStrategy "json") (gt (len .Params) 1)}}
+		{{template "standardBody" (dict "Method" . "Engine" $.Engine "Structs" $.Structs "PackageName" $.PackageName "JSONBulk" $jsonBulk "QueryErrors" $.QueryErrors)}}
 	{{end}}
+	{{- end}}
 }
 {{end}}
 
+{{define "zeroValues"}}
+	{{- $retType := firstReturnType .Returns -}}
+	{{- if .ReturnsSelf}}nil, {{else if not .HasValue}}{{else if isSlice $retType}}nil, {{else if isDomainStruct .ReturnElem}}{{.ReturnElem}}{}, {{else}}{{zeroValue $retType}}, {{end -}}
+{{end}}
+
+{{define "bulkChunk"}}
+	{{- $method := .Method -}}
+	{{- $ctx := (index .Method.Params 0).Name -}}
+	{{- $arg := (index .Method.Params 1).Name -}}
+	{{- $retType := firstReturnType .Method.Returns}}
+	if len({{$arg}}.{{.SliceField.Name}}) > {{.Method.BulkChunk}} {
+		// @bulk-chunk: send the slices in chunks of {{.Method.BulkChunk}} elements, each chunk
+		// going through this method again.
+		{{- if .Method.HasValue}}
+		var items []{{trimPrefix $retType "[]"}}
+		{{end}}
+		err = w.runBulk({{$ctx}}, func(a *{{.Engine.Package}}.Adapter) error {
+			tw := &{{.Engine.Name}}Wrapper{adapter: a}
+			for start := 0; start < len({{$arg}}.{{.SliceField.Name}}); start += {{.Method.BulkChunk}} {
+				if err := {{$ctx}}.Err(); err != nil {
+					return &BulkError{Method: "{{$method.Name}}", Index: start, Err: err}
+				}
+
+				end := min(start+{{.Method.BulkChunk}}, len({{$arg}}.{{.SliceField.Name}}))
+				chunk := {{$arg}}
+				{{- range .Struct.Fields}}
+					{{- if and (isSlice .Type) (ne .Type "[]byte")}}
+				chunk.{{.Name}} = {{$arg}}.{{.Name}}[start:end]
+					{{- end}}
+				{{- end}}
+				{{if .Method.HasValue}}res, {{end}}err := tw.{{.Method.Name}}({{range $i, $p := .Method.Params}}{{if $i}}, {{end}}{{if eq $i 1}}chunk{{else}}{{$p.Name}}{{end}}{{end}})
+				if err != nil {
+					return &BulkError{Method: "{{$method.Name}}", Index: start, Err: err}
+				}
+				{{- if isSlice $retType}}
+				items = append(items, res...)
+				{{- else if .Method.HasValue}}
+				items = append(items, res)
+				{{- end}}
+			}
+			return nil
+		})
+		{{- if not .Method.HasValue}}
+		return err
+		{{- else}}
+		if err != nil {
+			return {{template "zeroValues" .Method}}err
+		}
+		{{- if isSlice $retType}}
+		return items, nil
+		{{- else}}
+		return items[0], nil
+		{{- end}}
+		{{- end}}
+	}
+{{end}}
+
+{{define "callArgs"}}
+	{{- if .JSONBulk}}{{jsonBulkCall .Method}}{{else}}{{joinParamsCall .Method.Params .Engine.Package .Method.Name}}{{end -}}
+{{end}}
+
 {{define "standardBody"}}
 	{{- $method := .Method -}}
 	{{- $methodParams := .Method.Params -}}
-	{{- if and .Engine.IsPostgres (gt (len .Method.Params) 1) -}}
+	{{- if and (or .Engine.IsPostgres .JSONBulk) (gt (len .Method.Params) 1) -}}
 		{{- $pType := (index .Method.Params 1).Type -}}
 		{{- $sInfo := getStruct $pType -}}
 		{{- if and (ne $sInfo.Name "") (hasSliceField $sInfo) -}}
@@ -184,17 +1143,32 @@ func (w *{{$.Engine.Name}}Wrapper) {{.Name}}({{joinParamsSignature .Params}}) ({
 			{{- range $sInfo.Fields -}}
 				{{- if and (isSlice .Type) (ne .Name $sliceField.Name) -}}
 		if len({{(index $methodParams 1).Name}}.{{.Name}}) != len({{(index $methodParams 1).Name}}.{{$sliceField.Name}}) {
-			{{$retType := firstReturnType $method.Returns}}
-			return {{if $method.ReturnsSelf}}nil, {{else if not $method.HasValue}}{{else if isSlice $retType}}nil, {{else if isDomainStruct $method.ReturnElem}}{{$method.ReturnElem}}{}, {{else}}{{zeroValue $retType}}, {{end}}ErrMismatchedSlices
+			return {{template "zeroValues" $method}}ErrMismatchedSlices
 		}
 				{{- end -}}
 			{{- end -}}
+			{{- if and .Engine.IsPostgres .Method.BulkChunk}}
+				{{template "bulkChunk" (dict "Method" .Method "Engine" .Engine "SliceField" $sliceField "Struct" $sInfo)}}
+			{{- end -}}
 		{{- end -}}
 	{{- end -}}
+	{{- if .JSONBulk}}
+		{{- $arg := (index .Method.Params 1).Name}}
+
+		// @bulk-strategy json: the slices are sent as JSON arrays, expanded by the query.
+		{{- range (getStruct (index .Method.Params 1).Type).Fields}}
+			{{- if isBulkSlice .Type}}
+		{{jsonVar .Name}}, err := json.Marshal({{$arg}}.{{.Name}})
+		if err != nil {
+			return {{template "zeroValues" $method}}err
+		}
+			{{- end}}
+		{{- end}}
+	{{- end}}
 	{{if and .Engine.IsMySQL .Method.IsCreate}}
 		// MySQL does not support RETURNING for INSERTs.
 		// We insert, get LastInsertId, and then fetch the object.
-		res, err := w.adapter.{{.Method.Name}}({{joinParamsCall .Method.Params .Engine.Package .Method.Name}})
+		res, err := w.adapter.{{.Method.Name}}({{template "callArgs" .}})
 		if err != nil {
 			return {{.Method.ReturnElem}}{}, err
 		}
@@ -205,6 +1179,9 @@ func (w *{{$.Engine.Name}}Wrapper) {{.Name}}({{joinParamsSignature .Params}}) ({
 		}
 
 		nf, err := w.Get{{.Method.ReturnElem}}ByID(ctx, id)
+		{{- if .QueryErrors}}
+		err = unwrapQueryError(err) // reported in this method's QueryError, not the lookup's
+		{{- end}}
 		if err == nil {
 			return nf, nil
 		}
@@ -215,12 +1192,25 @@ func (w *{{$.Engine.Name}}Wrapper) {{.Name}}({{joinParamsSignature .Params}}) ({
 		// MySQL REPEATABLE READ: if ON DUPLICATE KEY UPDATE fired (another transaction
 		// already committed this row), the current transaction's MVCC snapshot may not
 		// see it via GetByID. Fall back to a non-transactional lookup on the raw DB
-		// connection, which always reads the latest committed data.
-		return (&mysqlWrapper{adapter: {{.Engine.Package}}.NewAdapter(w.adapter.DB())}).Get{{.Method.ReturnElem}}ByID(ctx, id)
+		// connection, which always reads the latest committed data. Outside a
+		// transaction the lookup above already did.
+		if w.currentTx() == nil {
+			return {{.Method.ReturnElem}}{}, err
+		}
+
+		{{- $lookup := printf "(&mysqlWrapper{adapter: %s.NewAdapter(w.adapter.DB())}).Get%sByID(ctx, id)" .Engine.Package .Method.ReturnElem}}
+
+		{{if .QueryErrors -}}
+		nf, err = {{$lookup}}
+
+		return nf, unwrapQueryError(err)
+		{{- else -}}
+		return {{$lookup}}
+		{{- end}}
 	{{else if and .Engine.IsMySQL .Method.IsUpdate}}
 		// MySQL does not support RETURNING for UPDATEs.
 		// We update, and then fetch the object by its unique key (assumed to be the first param after context, or we try by Hash if it exists).
-		res, err := w.adapter.{{.Method.Name}}({{joinParamsCall .Method.Params .Engine.Package .Method.Name}})
+		res, err := w.adapter.{{.Method.Name}}({{template "callArgs" .}})
 		if err != nil {
 			return {{.Method.ReturnElem}}{}, err
 		}
@@ -233,22 +1223,32 @@ func (w *{{$.Engine.Name}}Wrapper) {{.Name}}({{joinParamsSignature .Params}}) ({
 			return {{.Method.ReturnElem}}{}, ErrNotFound
 		}
 
-		{{if hasParam "id" .Method.Params}}
-		return w.Get{{.Method.ReturnElem}}ByID(ctx, id)
-		{{else if hasParam "hash" .Method.Params}}
-		return w.Get{{.Method.ReturnElem}}ByHash(ctx, hash)
-		{{else if hasParam "key" .Method.Params}}
-		return w.Get{{.Method.ReturnElem}}ByKey(ctx, key)
-		{{else if paramHasField "arg" "ID" .Method.Params $.Structs}}
-		return w.Get{{.Method.ReturnElem}}ByID(ctx, arg.ID)
-		{{else if paramHasField "arg" "Hash" .Method.Params $.Structs}}
-		return w.Get{{.Method.ReturnElem}}ByHash(ctx, arg.Hash)
-		{{else if paramHasField "arg" "Key" .Method.Params $.Structs}}
-		return w.Get{{.Method.ReturnElem}}ByKey(ctx, arg.Key)
-		{{else}}
+		{{- $lookup := ""}}
+		{{- if hasParam "id" .Method.Params}}
+			{{- $lookup = printf "w.Get%sByID(ctx, id)" .Method.ReturnElem}}
+		{{- else if hasParam "hash" .Method.Params}}
+			{{- $lookup = printf "w.Get%sByHash(ctx, hash)" .Method.ReturnElem}}
+		{{- else if hasParam "key" .Method.Params}}
+			{{- $lookup = printf "w.Get%sByKey(ctx, key)" .Method.ReturnElem}}
+		{{- else if paramHasField "arg" "ID" .Method.Params $.Structs}}
+			{{- $lookup = printf "w.Get%sByID(ctx, arg.ID)" .Method.ReturnElem}}
+		{{- else if paramHasField "arg" "Hash" .Method.Params $.Structs}}
+			{{- $lookup = printf "w.Get%sByHash(ctx, arg.Hash)" .Method.ReturnElem}}
+		{{- else if paramHasField "arg" "Key" .Method.Params $.Structs}}
+			{{- $lookup = printf "w.Get%sByKey(ctx, arg.Key)" .Method.ReturnElem}}
+		{{- end}}
+
+		{{if not $lookup}}
 		// Fallback to error if we can't easily fetch it by a common key.
 		return {{.Method.ReturnElem}}{}, errors.New("cannot fetch updated object: no common unique key parameter (id, hash, key) found")
-		{{end}}
+		{{- else if .QueryErrors}}
+		// Reported in this method's QueryError, not the lookup's.
+		updated, err := {{$lookup}}
+
+		return updated, unwrapQueryError(err)
+		{{- else}}
+		return {{$lookup}}
+		{{- end}}
 
 	{{else if .Method.IsSynthetic}}
 		{{- $tableName := getTableName .Method.ReturnElem -}}
@@ -260,7 +1260,7 @@ func (w *{{$.Engine.Name}}Wrapper) {{.Name}}({{joinParamsSignature .Params}}) ({
 		query := "SELECT {{range $i, $f := $targetStruct.Fields}}{{if $i}}, {{end}}{{quote $.Engine (toSnakeCase $f.Name)}}{{end}} FROM {{$tableName}} WHERE id = {{$placeholder}}"
 		row := w.adapter.DBTX().QueryRowContext(ctx, query, id)
 		var res {{.Engine.Package}}.{{.Method.ReturnElem}}
-		err := row.Scan(
+		err = row.Scan(
 			{{range $targetField := $targetStruct.Fields}}
 				&res.{{$targetField.Name}},
 			{{end}}
@@ -275,17 +1275,9 @@ func (w *{{$.Engine.Name}}Wrapper) {{.Name}}({{joinParamsSignature .Params}}) ({
 		// Convert to Domain Struct
 		{{$domainStruct := getStruct .Method.ReturnElem}}
 		return {{.Method.ReturnElem}}{
-			{{range $domainField := $domainStruct.Fields}}
-				{{$sourceField := dict "Name" ""}}
-				{{range $sf := $targetStruct.Fields}}
-					{{if eq $sf.Name $domainField.Name}}
-						{{$sourceField = $sf}}
-					{{end}}
-				{{end}}
-				{{if ne $sourceField.Name ""}}
-					{{generateFieldConversion $domainField.Name $domainField.Type $sourceField.Type (printf "res.%s" $sourceField.Name)}},
-				{{end}}
-			{{end}}
+			{{- range convertStructFields .Method.Name $domainStruct $targetStruct "res."}}
+			{{.}},
+			{{- end}}
 		}, nil
 	{{else}}
 
@@ -293,24 +1285,58 @@ func (w *{{$.Engine.Name}}Wrapper) {{.Name}}({{joinParamsSignature .Params}}) ({
 		{{- $retType := firstReturnType .Method.Returns -}}
 		{{- $targetMethod := getTargetMethod .Method.Name -}}
 		{{- $targetRetType := firstReturnType $targetMethod.Returns -}}
+		{{- /* sqlc may name the engine result type differently (e.g. GetBookRow vs Book) */ -}}
+		{{- $targetRetElem := trimPrefix $targetRetType "[]" -}}
+		{{- if eq $targetRetElem "" -}}
+			{{- $targetRetElem = .Method.ReturnElem -}}
+		{{- end -}}
 
-		{{if not .Method.HasValue}}
+		{{- $adapter := "w.adapter" -}}
+		{{- if and .Engine.IsSQLite $targetMethod.IsReadOnly (not .Method.Primary) -}}
+			{{- $adapter = "w.readAdapter()" -}}
+		{{- end -}}
+
+		{{if .Method.RequireRows}}
+			{{- if eq $targetRetType "sql.Result"}}
+			res, err := w.adapter.{{.Method.Name}}({{template "callArgs" .}})
+			if err != nil {
+				return err
+			}
+
+			rows, err := res.RowsAffected()
+			if err != nil {
+				return err
+			}
+			{{- else}}
+			rows, err := w.adapter.{{.Method.Name}}({{template "callArgs" .}})
+			if err != nil {
+				return err
+			}
+			{{- end}}
+
+			// @require-rows: nothing matched when no row was affected.
+			if rows == 0 {
+				return ErrNotFound
+			}
+
+			return nil
+		{{else if not .Method.HasValue}}
 			{{if .Method.ReturnsError}}
-				return w.adapter.{{.Method.Name}}({{joinParamsCall .Method.Params .Engine.Package .Method.Name}})
+				return w.adapter.{{.Method.Name}}({{template "callArgs" .}})
 			{{else}}
-				w.adapter.{{.Method.Name}}({{joinParamsCall .Method.Params .Engine.Package .Method.Name}})
+				w.adapter.{{.Method.Name}}({{template "callArgs" .}})
 				return
 			{{end}}
 		{{else}}
-			res{{if .Method.ReturnsError}}, err{{end}} := w.adapter.{{.Method.Name}}({{joinParamsCall .Method.Params .Engine.Package .Method.Name}})
+			res{{if .Method.ReturnsError}}, err{{end}} := {{$adapter}}.{{.Method.Name}}({{template "callArgs" .}})
 			{{if .Method.ReturnsError}}
 				if err != nil {
 					{{if and .Method.HasValue (not (isSlice $retType)) (or (isDomainStruct .Method.ReturnElem) .Method.ReturnsSelf)}}
 						if errors.Is(err, sql.ErrNoRows) {
-							return {{if .Method.ReturnsSelf}}nil, {{else if isSlice $retType}}nil, {{else if isDomainStruct .Method.ReturnElem}}{{.Method.ReturnElem}}{}, {{else}}{{zeroValue $retType}}, {{end}}ErrNotFound
+							return {{template "zeroValues" .Method}}ErrNotFound
 						}
 					{{end}}
-					return {{if .Method.ReturnsSelf}}nil, {{else if isSlice $retType}}nil, {{else if isDomainStruct .Method.ReturnElem}}{{.Method.ReturnElem}}{}, {{else}}{{zeroValue $retType}}, {{end}}err
+					return {{template "zeroValues" .Method}}err
 				}
 			{{end}}
 
@@ -323,19 +1349,11 @@ func (w *{{$.Engine.Name}}Wrapper) {{.Name}}({{joinParamsSignature .Params}}) ({
 					items := make([]{{.Method.ReturnElem}}, len(res))
 					for i, v := range res {
 						{{$targetStruct := getStruct .Method.ReturnElem}}
-						{{$sourceStruct := getTargetStruct .Method.ReturnElem}}
+						{{$sourceStruct := getTargetStruct $targetRetElem}}
 						items[i] = {{.Method.ReturnElem}}{
-							{{range $targetField := $targetStruct.Fields}}
-								{{$sourceField := dict "Name" ""}}
-								{{range $sf := $sourceStruct.Fields}}
-									{{if eq $sf.Name $targetField.Name}}
-										{{$sourceField = $sf}}
-									{{end}}
-								{{end}}
-								{{if ne $sourceField.Name ""}}
-									{{generateFieldConversion $targetField.Name $targetField.Type $sourceField.Type (printf "v.%s" $sourceField.Name)}},
-								{{end}}
-							{{end}}
+							{{- range convertStructFields .Method.Name $targetStruct $sourceStruct "v."}}
+							{{.}},
+							{{- end}}
 						}
 					}
 					return items{{if .Method.ReturnsError}}, nil{{end}}
@@ -346,19 +1364,11 @@ func (w *{{$.Engine.Name}}Wrapper) {{.Name}}({{joinParamsSignature .Params}}) ({
 			{{else if isDomainStruct .Method.ReturnElem}}
 				// Convert Single Domain Struct
 				{{$targetStruct := getStruct .Method.ReturnElem}}
-				{{$sourceStruct := getTargetStruct .Method.ReturnElem}}
+				{{$sourceStruct := getTargetStruct $targetRetElem}}
 				return {{.Method.ReturnElem}}{
-					{{range $targetField := $targetStruct.Fields}}
-						{{$sourceField := dict "Name" ""}}
-						{{range $sf := $sourceStruct.Fields}}
-							{{if eq $sf.Name $targetField.Name}}
-								{{$sourceField = $sf}}
-							{{end}}
-						{{end}}
-						{{if ne $sourceField.Name ""}}
-							{{generateFieldConversion $targetField.Name $targetField.Type $sourceField.Type (printf "res.%s" $sourceField.Name)}},
-						{{end}}
-					{{end}}
+					{{- range convertStructFields .Method.Name $targetStruct $sourceStruct "res."}}
+					{{.}},
+					{{- end}}
 				}{{if .Method.ReturnsError}}, nil{{end}}
 			{{else}}
 				// Return Primitive / *sql.DB / etc
@@ -375,6 +1385,10 @@ func (w *{{$.Engine.Name}}Wrapper) {{.Name}}({{joinParamsSignature .Params}}) ({
 	{{end}}
 {{end}}
 
+{{if .Engine.IsSQLite -}}
+// WithTx scopes every query to tx, including read-only ones that would otherwise use the
+// reader pool.
+{{end -}}
 func (w *{{.Engine.Name}}Wrapper) WithTx(tx *sql.Tx) Querier {
 	res := w.adapter.WithTx(tx)
 	return &{{.Engine.Name}}Wrapper{adapter: res}
@@ -383,4 +1397,130 @@ func (w *{{.Engine.Name}}Wrapper) WithTx(tx *sql.Tx) Querier {
 func (w *{{.Engine.Name}}Wrapper) DB() *sql.DB {
 	return w.adapter.DB()
 }
+
+func (w *{{.Engine.Name}}Wrapper) WithDBTX(db DBTX) Querier {
+	return &{{.Engine.Name}}Wrapper{adapter: w.adapter.WithDBTX(db)}
+}
+
+// {{.Engine.Name}}TxSavepoints numbers the savepoints of BeginTx calls nested in a transaction,
+// so that every level of nesting releases or rolls back its own savepoint.
+var {{.Engine.Name}}TxSavepoints atomic.Uint64
+
+// {{.Engine.Name}}TxWrapper is the TxQuerier returned by BeginTx.
+type {{.Engine.Name}}TxWrapper struct {
+	*{{.Engine.Name}}Wrapper
+	ctx       context.Context // context of BeginTx, as Commit and Rollback take none
+	tx        *sql.Tx
+	savepoint string // nested BeginTx: Commit and Rollback apply to this savepoint of tx
+	done      bool
+}
+
+func (w *{{.Engine.Name}}Wrapper) BeginTx(ctx context.Context, opts *sql.TxOptions) (TxQuerier, error) {
+	if tx := w.currentTx(); tx != nil {
+		name := fmt.Sprintf("sqlc_multi_db_begin_%d", {{.Engine.Name}}TxSavepoints.Add(1))
+
+		if _, err := tx.ExecContext(ctx, "SAVEPOINT "+name); err != nil {
+			return nil, w.classifyError(err)
+		}
+
+		return &{{.Engine.Name}}TxWrapper{ {{- .Engine.Name}}Wrapper: w, ctx: ctx, tx: tx, savepoint: name}, nil
+	}
+
+	tx, err := w.beginTx(ctx, w.txOptions(opts))
+	if err != nil {
+		return nil, w.classifyError(err)
+	}
+
+	return &{{.Engine.Name}}TxWrapper{ {{- .Engine.Name}}Wrapper: &{{.Engine.Name}}Wrapper{adapter: w.adapter.WithTx(tx)}, ctx: ctx, tx: tx}, nil
+}
+
+func (t *{{.Engine.Name}}TxWrapper) Commit() error {
+	if t.savepoint == "" {
+		return t.classifyError(t.tx.Commit())
+	}
+
+	if t.done {
+		return sql.ErrTxDone
+	}
+
+	t.done = true
+
+	_, err := t.tx.ExecContext(t.ctx, "RELEASE SAVEPOINT "+t.savepoint)
+
+	return t.classifyError(err)
+}
+
+func (t *{{.Engine.Name}}TxWrapper) Rollback() error {
+	if t.savepoint == "" {
+		return t.tx.Rollback()
+	}
+
+	if t.done {
+		return sql.ErrTxDone
+	}
+
+	t.done = true
+
+	if _, err := t.tx.ExecContext(t.ctx, "ROLLBACK TO SAVEPOINT "+t.savepoint); err != nil {
+		return err
+	}
+
+	_, err := t.tx.ExecContext(t.ctx, "RELEASE SAVEPOINT "+t.savepoint)
+
+	return err
+}
+
+// beginTx begins a transaction on the executor set with WithDBTX when it can begin one,
+// like a *sql.Conn, and on DB() otherwise.
+func (w *{{.Engine.Name}}Wrapper) beginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+	if conn, ok := w.adapter.DBTX().(interface {
+		BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+	}); ok {
+		return conn.BeginTx(ctx, opts)
+	}
+
+	return w.adapter.DB().BeginTx(ctx, opts)
+}
+
+// currentTx returns the transaction the wrapper is scoped to with WithTx, if any.
+func (w *{{.Engine.Name}}Wrapper) currentTx() *sql.Tx {
+	tx, _ := w.adapter.DBTX().(*sql.Tx)
+
+	return tx
+}
+
+// txOptions maps opts to the isolation levels {{.Engine.ExportedName}} supports.
+func (w *{{.Engine.Name}}Wrapper) txOptions(opts *sql.TxOptions) *sql.TxOptions {
+	if opts == nil {
+		return nil
+	}
+{{- if .Engine.IsSQLite}}
+
+	// SQLite transactions are always serializable.
+	return &sql.TxOptions{ReadOnly: opts.ReadOnly}
+{{- else if or .Engine.IsPostgres .Engine.IsMySQL}}
+
+	mapped := *opts
+
+	switch opts.Isolation {
+	case sql.LevelWriteCommitted:
+		mapped.Isolation = sql.LevelReadCommitted
+	case sql.LevelSnapshot:
+		// {{if .Engine.IsPostgres}}PostgreSQL{{else}}InnoDB{{end}} REPEATABLE READ reads from a snapshot taken at the first query.
+		mapped.Isolation = sql.LevelRepeatableRead
+	case sql.LevelLinearizable:
+		mapped.Isolation = sql.LevelSerializable
+	}
+
+	return &mapped
+{{- else}}
+
+	return opts
+{{- end}}
+}
+
+// classifyError classifies an error returned while beginning or committing a transaction.
+func (w *{{.Engine.Name}}Wrapper) classifyError(err error) error {
+	return classify{{.Engine.ExportedName}}Error(err)
+}
 `
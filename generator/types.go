@@ -1,5 +1,59 @@
 package generator
 
+import (
+	"fmt"
+	"strings"
+)
+
+// MethodSet selects which methods make up the unified Querier.
+type MethodSet string
+
+const (
+	// MethodSetSource uses the methods of the source querier only (the default).
+	MethodSetSource MethodSet = "source"
+	// MethodSetUnion uses the methods of every engine; methods missing on some engines are
+	// stubbed there with ErrNotSupported.
+	MethodSetUnion MethodSet = "union"
+	// MethodSetIntersection uses the source methods available on every engine.
+	MethodSetIntersection MethodSet = "intersection"
+)
+
+// BulkInference controls whether bulk methods are inferred from plural method names.
+type BulkInference string
+
+const (
+	// BulkInferenceAuto infers bulk methods unless Options.Strict is set (the default).
+	BulkInferenceAuto BulkInference = "auto"
+	// BulkInferenceOn always infers bulk methods.
+	BulkInferenceOn BulkInference = "on"
+	// BulkInferenceOff only treats methods annotated with @bulk-for as bulk methods.
+	BulkInferenceOff BulkInference = "off"
+)
+
+// Options configures a generator run.
+type Options struct {
+	Strict    bool          // Fail generation on any dropped or positionally matched struct field
+	Warn      bool          // Print field mapping diagnostics without failing
+	Methods   MethodSet     // Methods of the unified Querier; defaults to MethodSetSource
+	InferBulk BulkInference // Plural bulk method inference; defaults to BulkInferenceAuto
+	// QueryErrors wraps every error returned by a wrapper in a QueryError naming the method
+	// and engine. Errors must then be compared with errors.Is rather than ==.
+	QueryErrors bool
+}
+
+// inferBulk reports whether bulk methods should be inferred from plural method names.
+func (o Options) inferBulk() bool {
+	switch o.InferBulk {
+	case BulkInferenceOn:
+		return true
+	case BulkInferenceOff:
+		return false
+	case "", BulkInferenceAuto:
+	}
+
+	return !o.Strict
+}
+
 // Engine configuration.
 type Engine struct {
 	Name    string // e.g. "sqlite"
@@ -8,6 +62,36 @@ type Engine struct {
 
 func (e Engine) IsMySQL() bool    { return e.Name == "mysql" }
 func (e Engine) IsPostgres() bool { return e.Name == "postgres" }
+func (e Engine) IsSQLite() bool   { return e.Name == "sqlite" }
+
+// ExportedName returns the engine name as used in exported identifiers (e.g. "Postgres"
+// in PostgresQuerier).
+func (e Engine) ExportedName() string {
+	switch e.Name {
+	case "sqlite":
+		return "SQLite"
+	case "postgres":
+		return "Postgres"
+	case "mysql":
+		return "MySQL"
+	}
+
+	if e.Name == "" {
+		return ""
+	}
+
+	return strings.ToUpper(e.Name[:1]) + e.Name[1:]
+}
+
+// Extension describes the engine-specific interface generated for methods annotated with
+// @engine-only.
+type Extension struct {
+	Engine  Engine
+	Methods []MethodInfo
+}
+
+// InterfaceName returns the name of the generated interface (e.g. "PostgresQuerier").
+func (x Extension) InterfaceName() string { return x.Engine.ExportedName() + typeQuerier }
 
 // MethodInfo holds extracted data from the AST.
 type MethodInfo struct {
@@ -21,8 +105,52 @@ type MethodInfo struct {
 	ReturnsSelf  bool   // Does it return the wrapper type (like WithTx)?
 	HasValue     bool   // Does it return a value (non-error)?
 	Docs         []string
-	BulkFor      string // Extracted from @bulk-for annotation
-	IsSynthetic  bool   // Is this method automatically generated?
+	BulkFor      string   // Extracted from @bulk-for annotation
+	BulkChunk    int      // Extracted from @bulk-chunk annotation; 0 means no chunking
+	BulkStrategy string   // Extracted from @bulk-strategy annotation: values (default), loop or json
+	IsSynthetic  bool     // Is this method automatically generated?
+	IsReadOnly   bool     // Does its SQL only read (see isReadOnlyQuery)?
+	RequireRows  bool     // Extracted from @require-rows: return ErrNotFound when no row is affected
+	Primary      bool     // Extracted from @primary: never route to a replica or the SQLite reader
+	Replica      bool     // Extracted from @replica: route to a replica even if not detected as read-only
+	Engines      []string // Extracted from @engines annotation; empty means all engines
+	SkipEngines  []string // Extracted from @skip-engine annotation
+	EngineOnly   []string // Extracted from @engine-only annotation; kept off the unified Querier
+}
+
+// ReadsFromReplica reports whether RoutingQuerier sends the method to the replica.
+func (m MethodInfo) ReadsFromReplica() bool { return (m.IsReadOnly || m.Replica) && !m.Primary }
+
+// SupportsEngine reports whether the method is available on the named engine according
+// to its @engines, @skip-engine and @engine-only annotations.
+func (m MethodInfo) SupportsEngine(name string) bool {
+	if len(m.EngineOnly) > 0 {
+		for _, e := range m.EngineOnly {
+			if e == name {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	for _, e := range m.SkipEngines {
+		if e == name {
+			return false
+		}
+	}
+
+	if len(m.Engines) == 0 {
+		return true
+	}
+
+	for _, e := range m.Engines {
+		if e == name {
+			return true
+		}
+	}
+
+	return false
 }
 
 type Param struct {
@@ -48,4 +176,22 @@ type FieldInfo struct {
 type PackageData struct {
 	Methods []MethodInfo
 	Structs map[string]StructInfo
+	// AdapterMembers holds NewAdapter and the Adapter methods of an engine package that
+	// declares its own Adapter. It is nil when the Adapter is generated.
+	AdapterMembers map[string]bool
+}
+
+// Diagnostic describes a struct field the generator could not map exactly: either no
+// source field was found and the field is left zero-valued, or it was only matched by
+// position.
+type Diagnostic struct {
+	Method string
+	Engine string
+	Struct string
+	Field  string
+	Reason string
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%s (%s): %s.%s: %s", d.Method, d.Engine, d.Struct, d.Field, d.Reason)
 }
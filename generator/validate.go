@@ -0,0 +1,309 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+)
+
+// validationProblem is an inconsistency between the source Querier and an engine package
+// that would make the generated wrapper fail to compile.
+type validationProblem struct {
+	Engine  string
+	Method  string
+	Message string
+}
+
+// wrapperAdapterMembers are the members of the engine Adapter the generated wrapper calls.
+// A hand-written Adapter must declare all of them, as the generated one does.
+var wrapperAdapterMembers = []string{"NewAdapter", "DB", "DBTX", "WithTx", "WithDBTX"}
+
+// validateEngines compares every engine package against the source methods and returns
+// the problems found, ordered by engine and then by method. Annotations naming engines
+// that are not configured are reported first.
+func validateEngines(
+	methods []MethodInfo, structs map[string]StructInfo, engines []Engine, engineData map[string]PackageData,
+) []validationProblem {
+	var problems []validationProblem
+
+	configured := make(map[string]bool, len(engines))
+	for _, engine := range engines {
+		configured[engine.Name] = true
+	}
+
+	for _, m := range methods {
+		names := append(append(append([]string{}, m.Engines...), m.SkipEngines...), m.EngineOnly...)
+		for _, name := range names {
+			if !configured[name] {
+				problems = append(problems, validationProblem{
+					Engine:  name,
+					Method:  m.Name,
+					Message: "annotation names an engine that is not configured",
+				})
+			}
+		}
+	}
+
+	for _, engine := range engines {
+		engData := engineData[engine.Name]
+
+		if msg := validateAdapter(engData.AdapterMembers); msg != "" {
+			problems = append(problems, validationProblem{Engine: engine.Name, Method: "Adapter", Message: msg})
+		}
+
+		for _, m := range methods {
+			// Unsupported methods are generated as ErrNotSupported stubs or, for
+			// @engine-only methods, not generated at all. A stub can only report
+			// ErrNotSupported through an error return.
+			if !m.SupportsEngine(engine.Name) {
+				if len(m.EngineOnly) == 0 && !m.ReturnsError {
+					problems = append(problems, validationProblem{
+						Engine:  engine.Name,
+						Method:  m.Name,
+						Message: "is not supported here but has no error return for ErrNotSupported: return an error or use @engine-only",
+					})
+				}
+
+				continue
+			}
+
+			for _, msg := range validateMethod(engine, m, methods, structs, engData) {
+				problems = append(problems, validationProblem{Engine: engine.Name, Method: m.Name, Message: msg})
+			}
+		}
+	}
+
+	return problems
+}
+
+// validateMethod checks a single source method against the engine package. methods and
+// structs are the source package's.
+func validateMethod(
+	engine Engine, m MethodInfo, methods []MethodInfo, structs map[string]StructInfo, engData PackageData,
+) []string {
+	// Synthetic methods query the engine model directly.
+	if m.IsSynthetic {
+		if _, ok := engData.Structs[m.ReturnElem]; !ok {
+			return []string{fmt.Sprintf("model struct %s.%s is missing", engine.Package, m.ReturnElem)}
+		}
+
+		return nil
+	}
+
+	if m.BulkStrategy == bulkStrategyJSON && engine.IsMySQL() && m.HasValue {
+		return []string{"@bulk-strategy json needs the bulk method to return only an error on MySQL, which has no RETURNING"}
+	}
+
+	// Bulk methods are emulated on SQLite and MySQL by calling the singular method, so the
+	// engine's own bulk method is never used, unless it expands JSON arrays.
+	if m.BulkFor != "" && !engine.IsPostgres() && m.BulkStrategy != bulkStrategyJSON {
+		if _, ok := findMethod(engData.Methods, m.BulkFor); !ok {
+			return []string{fmt.Sprintf("@bulk-for method %s is missing from %s.Querier", m.BulkFor, engine.Package)}
+		}
+
+		if msg := validateBulkLoop(m, methods, structs); msg != "" {
+			return []string{msg}
+		}
+
+		return nil
+	}
+
+	target, ok := findMethod(engData.Methods, m.Name)
+	if !ok {
+		return []string{fmt.Sprintf("method is missing from %s.Querier", engine.Package)}
+	}
+
+	var msgs []string
+
+	if msg := validateArity(m, target); msg != "" {
+		msgs = append(msgs, msg)
+	}
+
+	if msg := validateReturns(engine, m, target); msg != "" {
+		msgs = append(msgs, msg)
+	}
+
+	for _, p := range target.Params {
+		if isDomainStructFunc(p.Type) {
+			if _, ok := engData.Structs[p.Type]; !ok {
+				msgs = append(msgs, fmt.Sprintf("param struct %s.%s is missing", engine.Package, p.Type))
+			}
+		}
+	}
+
+	if elem := strings.TrimPrefix(firstReturnType(target.Returns), "[]"); isDomainStruct(elem) && !m.ReturnsSelf {
+		if _, ok := engData.Structs[elem]; !ok {
+			msgs = append(msgs, fmt.Sprintf("model struct %s.%s is missing", engine.Package, elem))
+		}
+	}
+
+	return msgs
+}
+
+// validateAdapter reports the members the wrapper calls that a hand-written Adapter lacks.
+// members is nil when the Adapter is generated.
+func validateAdapter(members map[string]bool) string {
+	if members == nil {
+		return ""
+	}
+
+	var missing []string
+
+	for _, name := range wrapperAdapterMembers {
+		if !members[name] {
+			missing = append(missing, name)
+		}
+	}
+
+	if len(missing) == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf("the hand-written Adapter lacks %s, which the wrapper calls: "+
+		"add it, or delete the Adapter to have %s generated", strings.Join(missing, ", "), adapterFileName)
+}
+
+// validateBulkLoop reports a bulk method whose loop would drop values: when the singular
+// method takes a single value rather than a params struct, each iteration can only pass
+// the element of one slice field, so the bulk params struct must have no other field.
+func validateBulkLoop(m MethodInfo, methods []MethodInfo, structs map[string]StructInfo) string {
+	singular, ok := findMethod(methods, m.BulkFor)
+	if !ok || len(m.Params) < 2 {
+		return ""
+	}
+
+	if len(singular.Params) > 1 {
+		if _, ok := structs[singular.Params[1].Type]; ok {
+			return ""
+		}
+	}
+
+	bulk, ok := structs[m.Params[1].Type]
+	if !ok || !hasSliceField(bulk) {
+		return ""
+	}
+
+	slice := getSliceField(bulk)
+
+	var dropped []string
+
+	for _, f := range bulk.Fields {
+		if f.Name != slice.Name {
+			dropped = append(dropped, f.Name)
+		}
+	}
+
+	if len(dropped) == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf("@bulk-for %s takes a single value, so only %s.%s reaches it and %s would be dropped: "+
+		"make %s take a params struct", m.BulkFor, bulk.Name, slice.Name, strings.Join(dropped, ", "), m.BulkFor)
+}
+
+// validateArity reports parameter count differences that cannot be reconciled. When
+// either side groups its parameters in a struct, joinParamsCall maps them by name.
+func validateArity(m, target MethodInfo) string {
+	if len(m.Params) == len(target.Params) {
+		return ""
+	}
+
+	for _, p := range append(append([]Param{}, m.Params...), target.Params...) {
+		if isDomainStructFunc(p.Type) {
+			return ""
+		}
+	}
+
+	return fmt.Sprintf("takes %d parameter(s) but the engine method takes %d", len(m.Params), len(target.Params))
+}
+
+// returnShape describes a method's first return value: "none", "value" or "slice".
+func returnShape(m MethodInfo) string {
+	first := firstReturnType(m.Returns)
+
+	switch {
+	case first == "" || first == "error":
+		return "none"
+	case isSlice(first) && first != typeBytes:
+		return "slice"
+	default:
+		return "value"
+	}
+}
+
+// validateReturns reports return values the wrapper cannot convert.
+func validateReturns(engine Engine, m, target MethodInfo) string {
+	if m.ReturnsSelf {
+		return ""
+	}
+
+	targetFirst := firstReturnType(target.Returns)
+
+	if m.RequireRows {
+		if (targetFirst != "int64" && targetFirst != "sql.Result") || !hasReturn(target.Returns, "error") {
+			return fmt.Sprintf("returns %s but @require-rows needs a :execrows (int64) or :execresult (sql.Result) query",
+				joinReturns(target.Returns))
+		}
+
+		return ""
+	}
+
+	// MySQL emulates RETURNING with LastInsertId/RowsAffected on a :execresult query.
+	if engine.IsMySQL() && (m.IsCreate || m.IsUpdate) {
+		if targetFirst != "sql.Result" {
+			return fmt.Sprintf("returns %s but MySQL RETURNING emulation needs a :execresult query (sql.Result)", targetFirst)
+		}
+
+		return ""
+	}
+
+	if m.ReturnsError != hasReturn(target.Returns, "error") {
+		return "error return differs from the engine method"
+	}
+
+	if want, got := returnShape(m), returnShape(target); want != got {
+		return fmt.Sprintf("returns %s (%s) but the engine method returns %s (%s)",
+			joinReturns(m.Returns), want, joinReturns(target.Returns), got)
+	}
+
+	return ""
+}
+
+func hasReturn(returns []Return, t string) bool {
+	for _, r := range returns {
+		if r.Type == t {
+			return true
+		}
+	}
+
+	return false
+}
+
+func findMethod(methods []MethodInfo, name string) (MethodInfo, bool) {
+	for _, m := range methods {
+		if m.Name == name {
+			return m, true
+		}
+	}
+
+	return MethodInfo{}, false
+}
+
+// formatValidationReport renders problems as a single report grouped by engine.
+func formatValidationReport(problems []validationProblem) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "%d engine consistency problem(s), no files were written:", len(problems))
+
+	engine := ""
+
+	for _, p := range problems {
+		if p.Engine != engine {
+			engine = p.Engine
+			fmt.Fprintf(&b, "\n  %s:", engine)
+		}
+
+		fmt.Fprintf(&b, "\n    - %s: %s", p.Method, p.Message)
+	}
+
+	return b.String()
+}
@@ -11,7 +11,11 @@ import (
 	"github.com/kalbasit/sqlc-multi-db/generator"
 )
 
-var errInvalidEngineFormat = errors.New("invalid engine format: expected name:package")
+var (
+	errInvalidEngineFormat = errors.New("invalid engine format: expected name:package")
+	errInvalidMethodSet    = errors.New("invalid method set: expected source, union or intersection")
+	errInvalidBulkInfer    = errors.New("invalid bulk inference: expected auto, on or off")
+)
 
 type engineFlag []generator.Engine
 
@@ -35,13 +39,63 @@ func (e *engineFlag) Set(value string) error {
 	return nil
 }
 
+// choiceFlag is a flag accepting one of a fixed set of values.
+type choiceFlag[T ~string] struct {
+	value   *T
+	choices []T
+	err     error
+}
+
+func (c choiceFlag[T]) String() string {
+	if c.value == nil {
+		return ""
+	}
+
+	return string(*c.value)
+}
+
+func (c choiceFlag[T]) Set(value string) error {
+	for _, choice := range c.choices {
+		if T(value) == choice {
+			*c.value = choice
+
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: got %q", c.err, value)
+}
+
 func main() {
-	var engines engineFlag
+	var (
+		engines engineFlag
+		opts    generator.Options
+	)
 
 	flag.Var(&engines, "engine", "Engine in name:package format (repeatable)")
+	flag.BoolVar(&opts.Strict, "strict", false, "Fail when a struct field is dropped or only matched by position")
+	flag.BoolVar(&opts.Warn, "warn", false, "Report dropped or positionally matched struct fields without failing")
+	flag.BoolVar(&opts.QueryErrors, "query-errors", false,
+		"Wrap every error returned by a wrapper in a QueryError naming the method and engine")
+
+	opts.Methods = generator.MethodSetSource
+	flag.Var(choiceFlag[generator.MethodSet]{
+		value:   &opts.Methods,
+		choices: []generator.MethodSet{generator.MethodSetSource, generator.MethodSetUnion, generator.MethodSetIntersection},
+		err:     errInvalidMethodSet,
+	}, "methods", "Methods of the unified Querier: source, union or intersection of the engine Queriers")
+
+	opts.InferBulk = generator.BulkInferenceAuto
+	flag.Var(choiceFlag[generator.BulkInference]{
+		value:   &opts.InferBulk,
+		choices: []generator.BulkInference{generator.BulkInferenceAuto, generator.BulkInferenceOn, generator.BulkInferenceOff},
+		err:     errInvalidBulkInfer,
+	}, "infer-bulk", "Treat plural methods such as CreateUsers as bulk variants of CreateUser without @bulk-for:"+
+		" auto (on unless --strict), on or off")
 
 	flag.Usage = func() {
-		fmt.Fprintf(os.Stderr, "USAGE: %s [--engine name:package ...] /path/to/source/querier.go\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "USAGE: %s [flags] [--engine name:package ...] /path/to/source/querier.go\n", os.Args[0])
+		flag.PrintDefaults()
 	}
 
 	flag.Parse()
@@ -57,5 +111,5 @@ func main() {
 		log.Fatalf("stat(%q): %s", querierPath, err)
 	}
 
-	generator.Run(querierPath, []generator.Engine(engines))
+	generator.RunWithOptions(querierPath, []generator.Engine(engines), opts)
 }